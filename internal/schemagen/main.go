@@ -0,0 +1,230 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: main.go
+Description: go generate helper that turns the JSON Schema documents
+under schema/ into the allow-list/pattern constants the validator
+packages build on, so the schema stays the single source of truth.
+Author: tengzl33t
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+func main() {
+	schemaPath := flag.String("schema", "", "path to the JSON Schema document")
+	pkgName := flag.String("package", "", "package name for the generated file")
+	outPath := flag.String("out", "", "output file path")
+	var enums multiFlag
+	var patterns multiFlag
+	var ranges multiFlag
+	flag.Var(&enums, "enum", "name=/json/pointer/to/an/enum array, repeatable")
+	flag.Var(&patterns, "pattern", "name=/json/pointer/to/a pattern string, repeatable")
+	flag.Var(&ranges, "range", "name=/min/pointer,/max/pointer, repeatable")
+	flag.Parse()
+
+	if *schemaPath == "" || *pkgName == "" || *outPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: schemagen -schema <file> -package <name> -out <file> [-enum name=ptr]... [-pattern name=ptr]... [-range name=minPtr,maxPtr]...")
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by internal/schemagen from %s; DO NOT EDIT.\n\n", *schemaPath)
+	fmt.Fprintf(&b, "package %s\n\n", *pkgName)
+
+	type decl struct{ kind, name, body string }
+	var decls []decl
+
+	for _, spec := range enums {
+		name, ptr, err := splitSpec(spec)
+		if err != nil {
+			fail(err)
+		}
+		values, err := resolveStringArray(doc, ptr)
+		if err != nil {
+			fail(fmt.Errorf("enum %s: %w", name, err))
+		}
+		decls = append(decls, decl{"enum", name, formatStringSlice(values)})
+	}
+
+	for _, spec := range patterns {
+		name, ptr, err := splitSpec(spec)
+		if err != nil {
+			fail(err)
+		}
+		value, err := resolveString(doc, ptr)
+		if err != nil {
+			fail(fmt.Errorf("pattern %s: %w", name, err))
+		}
+		decls = append(decls, decl{"pattern", name, "`" + value + "`"})
+	}
+
+	for _, spec := range ranges {
+		name, ptrs, err := splitRangeSpec(spec)
+		if err != nil {
+			fail(err)
+		}
+		min, err := resolveNumber(doc, ptrs[0])
+		if err != nil {
+			fail(fmt.Errorf("range %s min: %w", name, err))
+		}
+		max, err := resolveNumber(doc, ptrs[1])
+		if err != nil {
+			fail(fmt.Errorf("range %s max: %w", name, err))
+		}
+		decls = append(decls, decl{"range", name, fmt.Sprintf("%d\n\nconst %sMax = %d", int(min), name, int(max))})
+	}
+
+	for _, d := range decls {
+		switch d.kind {
+		case "enum":
+			fmt.Fprintf(&b, "var Allowed%s = %s\n\n", d.name, d.body)
+		case "pattern":
+			fmt.Fprintf(&b, "const %sPattern = %s\n\n", d.name, d.body)
+		case "range":
+			fmt.Fprintf(&b, "const %sMin = %s\n\n", d.name, d.body)
+		}
+	}
+
+	if err := os.WriteFile(*outPath, []byte(b.String()), 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func splitSpec(spec string) (name, ptr string, err error) {
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid spec %q, want name=/json/pointer", spec)
+	}
+	return parts[0], parts[1], nil
+}
+
+func splitRangeSpec(spec string) (name string, ptrs [2]string, err error) {
+	name, rest, err := splitSpec(spec)
+	if err != nil {
+		return "", ptrs, err
+	}
+	parts := strings.SplitN(rest, ",", 2)
+	if len(parts) != 2 {
+		return "", ptrs, fmt.Errorf("invalid range spec %q, want name=/min/ptr,/max/ptr", spec)
+	}
+	return name, [2]string{parts[0], parts[1]}, nil
+}
+
+func resolvePointer(doc any, pointer string) (any, error) {
+	if pointer == "" || pointer == "/" {
+		return doc, nil
+	}
+	cur := doc
+	for _, tok := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		switch node := cur.(type) {
+		case map[string]any:
+			next, ok := node[tok]
+			if !ok {
+				return nil, fmt.Errorf("no such key %q in pointer %q", tok, pointer)
+			}
+			cur = next
+		case []any:
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("bad array index %q in pointer %q", tok, pointer)
+			}
+			cur = node[idx]
+		default:
+			return nil, fmt.Errorf("cannot descend into %q at pointer %q", tok, pointer)
+		}
+	}
+	return cur, nil
+}
+
+func resolveStringArray(doc any, pointer string) ([]string, error) {
+	node, err := resolvePointer(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	raw, ok := node.([]any)
+	if !ok {
+		return nil, fmt.Errorf("pointer %q is not an array", pointer)
+	}
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("pointer %q contains a non-string entry", pointer)
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+func resolveString(doc any, pointer string) (string, error) {
+	node, err := resolvePointer(doc, pointer)
+	if err != nil {
+		return "", err
+	}
+	s, ok := node.(string)
+	if !ok {
+		return "", fmt.Errorf("pointer %q is not a string", pointer)
+	}
+	return s, nil
+}
+
+func resolveNumber(doc any, pointer string) (float64, error) {
+	node, err := resolvePointer(doc, pointer)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := node.(float64)
+	if !ok {
+		return 0, fmt.Errorf("pointer %q is not a number", pointer)
+	}
+	return n, nil
+}
+
+func formatStringSlice(values []string) string {
+	var b strings.Builder
+	b.WriteString("[]string{")
+	for i, v := range values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strconv.Quote(v))
+	}
+	b.WriteString("}")
+	return b.String()
+}
@@ -0,0 +1,97 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: main_test.go
+Description: Tests for JSON Pointer resolution used to extract allow-lists
+from a schema document
+Author: tengzl33t
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustUnmarshal(t *testing.T, data string) any {
+	t.Helper()
+	var doc any
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	return doc
+}
+
+func TestResolvePointer(t *testing.T) {
+	doc := mustUnmarshal(t, `{"items":{"properties":{"cert_mode":{"enum":["san","classic"]}}}}`)
+
+	values, err := resolveStringArray(doc, "/items/properties/cert_mode/enum")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 2 || values[0] != "san" || values[1] != "classic" {
+		t.Fatalf("got %v, want [san classic]", values)
+	}
+}
+
+func TestResolvePointerMissingKey(t *testing.T) {
+	doc := mustUnmarshal(t, `{"items":{}}`)
+	if _, err := resolveStringArray(doc, "/items/properties/missing/enum"); err == nil {
+		t.Fatalf("expected an error for a missing key")
+	}
+}
+
+func TestResolvePointerArrayIndex(t *testing.T) {
+	doc := mustUnmarshal(t, `{"list":["a","b","c"]}`)
+	s, err := resolveString(doc, "/list/1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s != "b" {
+		t.Fatalf("got %q, want %q", s, "b")
+	}
+}
+
+func TestResolveNumber(t *testing.T) {
+	doc := mustUnmarshal(t, `{"minimum":1,"maximum":366}`)
+	min, err := resolveNumber(doc, "/minimum")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if min != 1 {
+		t.Fatalf("got %v, want 1", min)
+	}
+}
+
+func TestSplitSpec(t *testing.T) {
+	name, ptr, err := splitSpec("CertModes=/items/properties/cert_mode/enum")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "CertModes" || ptr != "/items/properties/cert_mode/enum" {
+		t.Fatalf("got (%q, %q)", name, ptr)
+	}
+}
+
+func TestSplitRangeSpec(t *testing.T) {
+	name, ptrs, err := splitRangeSpec("DaysToCollect=/min,/max")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "DaysToCollect" || ptrs[0] != "/min" || ptrs[1] != "/max" {
+		t.Fatalf("got (%q, %v)", name, ptrs)
+	}
+}
+
+func TestFormatStringSlice(t *testing.T) {
+	got := formatStringSlice([]string{"a", "b"})
+	want := `[]string{"a", "b"}`
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
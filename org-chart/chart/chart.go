@@ -0,0 +1,437 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: chart.go
+Description: Organization chart graph construction and rendering
+Author: tengzl33t
+*/
+
+package chart
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-graphviz"
+	"github.com/goccy/go-graphviz/cgraph"
+	"gopkg.in/yaml.v3"
+)
+
+type Person struct {
+	Title    string `yaml:"title"`
+	Position string `yaml:"position"`
+}
+
+type Group struct {
+	Title     string   `yaml:"title"`
+	Positions []string `yaml:"positions"`
+}
+
+// LineStyle distinguishes solid-line (direct) management from the
+// dotted/dashed reporting lines matrix orgs also need (mentorship,
+// cross-functional). A Person can appear as a report under several
+// Relations with different Managers to model multiple managers.
+type LineStyle string
+
+const (
+	LineSolid  LineStyle = "solid"
+	LineDotted LineStyle = "dotted"
+	LineDashed LineStyle = "dashed"
+)
+
+type Relation struct {
+	Manager string    `yaml:"manager"`
+	Report  []string  `yaml:"report"`
+	Type    LineStyle `yaml:"type"`
+	Label   string    `yaml:"label"`
+	Weight  *float64  `yaml:"weight"`
+}
+
+func (l LineStyle) graphvizStyle() string {
+	switch l {
+	case LineDotted, LineDashed:
+		return string(l)
+	default:
+		return string(LineSolid)
+	}
+}
+
+type Config struct {
+	People    map[string]Person `yaml:"people"`
+	Groups    map[string]Group  `yaml:"groups"`
+	Relations []Relation        `yaml:"relations"`
+}
+
+// LoadConfig reads and parses an org chart config in YAML form.
+func LoadConfig(r io.Reader) (Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Format selects one of the supported --format values. Everything but
+// "pdf" is handed straight to graphviz as its -T output format name;
+// "pdf" is rendered by shelling out to the system "dot" binary, since
+// the pure-Go renderer can't produce PDF itself.
+type Format string
+
+const (
+	FormatSVG Format = "svg"
+	FormatPNG Format = "png"
+	FormatPDF Format = "pdf"
+	FormatDOT Format = "dot"
+	FormatRaw Format = "json"
+)
+
+// Layout selects the graphviz layout engine.
+type Layout string
+
+const (
+	LayoutDot   Layout = "dot"
+	LayoutNeato Layout = "neato"
+	LayoutFDP   Layout = "fdp"
+	LayoutCirco Layout = "circo"
+)
+
+// RankDir selects the graph's rank direction.
+type RankDir string
+
+const (
+	RankDirTB RankDir = "TB"
+	RankDirLR RankDir = "LR"
+)
+
+type RenderOptions struct {
+	Format  Format
+	Layout  Layout
+	RankDir RankDir
+	Legend  bool
+}
+
+type nodeInfo struct {
+	Node        *cgraph.Node
+	IsCluster   bool
+	ClusterName string
+	RepNode     *cgraph.Node
+}
+
+func createEdges(cfg Config, nodes map[string]*nodeInfo, graph *cgraph.Graph) {
+	for _, relation := range cfg.Relations {
+		if len(relation.Report) == 0 {
+			println("Warning: Relation of " + relation.Manager + " has no report entries")
+			continue
+		}
+		fromInfo := nodes[relation.Manager]
+		for _, report := range relation.Report {
+			toInfo := nodes[report]
+
+			if fromInfo == nil || toInfo == nil {
+				println("Warning: Missing node for relation " + relation.Manager + "-> " + report)
+				continue
+			}
+
+			if fromInfo.RepNode == nil || toInfo.RepNode == nil {
+				println("Warning: Missing representative node for relation " + relation.Manager + "-> " + report)
+				continue
+			}
+
+			edge, err := graph.CreateEdgeByName("", fromInfo.RepNode, toInfo.RepNode)
+			if err != nil {
+				println("Error creating edge " + err.Error())
+				continue
+			}
+
+			if fromInfo.IsCluster {
+				edge.SetLogicalTail(fromInfo.ClusterName)
+			}
+			if toInfo.IsCluster {
+				edge.SetLogicalHead(toInfo.ClusterName)
+			}
+
+			edge.SetColor("#666666")
+			edge.SetMinLen(2)
+			edge.SetStyle(cgraph.EdgeStyle(relation.Type.graphvizStyle()))
+			if relation.Label != "" {
+				edge.SetLabel(relation.Label)
+			}
+			if relation.Weight != nil {
+				edge.SetPenWidth(*relation.Weight)
+			}
+		}
+	}
+}
+
+// createLegend adds a small cluster explaining what each line style
+// means, since real orgs end up with a mix of solid/dotted/dashed
+// relations that aren't self-explanatory on sight.
+func createLegend(graph *cgraph.Graph) error {
+	legend, err := graph.CreateSubGraphByName("cluster_legend")
+	if err != nil {
+		return err
+	}
+	legend.SetLabel("Legend")
+	legend.SetStyle("rounded")
+
+	entries := []struct {
+		style LineStyle
+		label string
+	}{
+		{LineSolid, "Direct management"},
+		{LineDotted, "Dotted-line / cross-functional"},
+		{LineDashed, "Mentorship"},
+	}
+
+	for i, entry := range entries {
+		fromName := fmt.Sprintf("legend_%d_from", i)
+		toName := fmt.Sprintf("legend_%d_to", i)
+
+		from, _ := legend.CreateNodeByName(fromName)
+		from.SetLabel("")
+		from.SetShape("point")
+		to, _ := legend.CreateNodeByName(toName)
+		to.SetLabel("")
+		to.SetShape("point")
+
+		edge, err := legend.CreateEdgeByName("", from, to)
+		if err != nil {
+			return err
+		}
+		edge.SetStyle(cgraph.EdgeStyle(entry.style.graphvizStyle()))
+		edge.SetLabel(entry.label)
+		edge.SetColor("#666666")
+	}
+
+	return nil
+}
+
+func createPerson(id string, person Person, graph *cgraph.Graph, nodes map[string]*nodeInfo) *cgraph.Node {
+	item, _ := graph.CreateNodeByName(id)
+	label := person.Title + "\\n" + person.Position
+	item.SetLabel(label)
+	item.SetShape("box")
+	item.SetStyle("rounded,filled")
+	item.SetFillColor("#E8F0FE")
+	nodes[id] = &nodeInfo{
+		Node:      item,
+		IsCluster: false,
+		RepNode:   item,
+	}
+	return item
+}
+
+func createGroups(cfg Config, nodes map[string]*nodeInfo, graph *cgraph.Graph) {
+	for _, id := range slices.Sorted(maps.Keys(cfg.Groups)) {
+		group := cfg.Groups[id]
+		if len(group.Positions) == 0 {
+			println("Warning: Group '" + id + "' has no positions")
+			continue
+		}
+
+		clusterName := "cluster_" + id
+		cluster, _ := graph.CreateSubGraphByName(clusterName)
+		cluster.SetLabel(group.Title)
+		cluster.SetStyle("rounded,filled")
+		cluster.SetBackgroundColor("#AAAAAA")
+
+		var posNodes []*cgraph.Node
+
+		for _, position := range group.Positions {
+			posNodes = append(posNodes, createPerson(position, cfg.People[position], cluster, nodes))
+		}
+
+		var repNode *cgraph.Node
+		if len(posNodes) > 0 {
+			middleIndex := len(posNodes) / 2
+			repNode = posNodes[middleIndex]
+		}
+
+		nodes[id] = &nodeInfo{
+			Node:        nil,
+			IsCluster:   true,
+			ClusterName: clusterName,
+			RepNode:     repNode,
+		}
+	}
+}
+
+func getGroupPositions(cfg Config) []string {
+	var positions []string
+	for _, v := range cfg.Groups {
+		positions = append(positions, v.Positions...)
+	}
+	return positions
+}
+
+func createPeople(cfg Config, nodes map[string]*nodeInfo, graph *cgraph.Graph) {
+	gropedPositions := getGroupPositions(cfg)
+
+	for _, id := range slices.Sorted(maps.Keys(cfg.People)) {
+		if !slices.Contains(gropedPositions, id) {
+			createPerson(id, cfg.People[id], graph, nodes)
+		}
+	}
+}
+
+func layoutEngine(layout Layout) graphviz.Layout {
+	switch layout {
+	case LayoutNeato:
+		return graphviz.NEATO
+	case LayoutFDP:
+		return graphviz.FDP
+	case LayoutCirco:
+		return graphviz.CIRCO
+	default:
+		return graphviz.DOT
+	}
+}
+
+// Render builds the graph described by cfg and writes it to w in the
+// requested format.
+func Render(ctx context.Context, gv *graphviz.Graphviz, cfg Config, opts RenderOptions, w io.Writer) error {
+	graph, err := gv.Graph()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = graph.Close() }()
+
+	rankDir := opts.RankDir
+	if rankDir == "" {
+		rankDir = RankDirTB
+	}
+	graph.SetRankDir(cgraph.RankDir(rankDir))
+	graph.SetCompound(true)
+
+	nodes := map[string]*nodeInfo{}
+
+	createPeople(cfg, nodes, graph)
+	createGroups(cfg, nodes, graph)
+	createEdges(cfg, nodes, graph)
+
+	if opts.Legend {
+		if err := createLegend(graph); err != nil {
+			return err
+		}
+	}
+
+	gv.SetLayout(layoutEngine(opts.Layout))
+
+	format := opts.Format
+	if format == "" {
+		format = FormatSVG
+	}
+
+	if format == FormatPDF {
+		return renderPDF(ctx, gv, graph, w)
+	}
+
+	return gv.Render(ctx, graph, graphviz.Format(format), w)
+}
+
+// renderPDF shells out to the system "dot" binary for PDF output, since
+// go-graphviz's pure-Go renderer doesn't support it.
+func renderPDF(ctx context.Context, gv *graphviz.Graphviz, graph *cgraph.Graph, w io.Writer) error {
+	var dotSource bytes.Buffer
+	if err := gv.Render(ctx, graph, graphviz.Format(FormatDOT), &dotSource); err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "dot", "-Tpdf")
+	cmd.Stdin = &dotSource
+	cmd.Stdout = w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("rendering pdf via system dot binary: %w", err)
+	}
+	return nil
+}
+
+// Lint returns warnings about cfg that don't prevent rendering but
+// usually indicate a mistake: cycles in the reporting graph, and people
+// who are defined but never referenced by a relation or a group.
+func Lint(cfg Config) []string {
+	var warnings []string
+	for _, cycle := range findCycles(cfg) {
+		warnings = append(warnings, "Warning: cycle in reporting graph: "+strings.Join(cycle, " -> "))
+	}
+	for _, id := range findOrphans(cfg) {
+		warnings = append(warnings, "Warning: person '"+id+"' is not referenced by any relation or group")
+	}
+	return warnings
+}
+
+func findCycles(cfg Config) [][]string {
+	reports := map[string][]string{}
+	for _, relation := range cfg.Relations {
+		reports[relation.Manager] = append(reports[relation.Manager], relation.Report...)
+	}
+
+	const (
+		unvisited = 0
+		visiting  = 1
+		done      = 2
+	)
+	state := map[string]int{}
+	var cycles [][]string
+
+	var visit func(id string, path []string)
+	visit = func(id string, path []string) {
+		switch state[id] {
+		case visiting:
+			start := slices.Index(path, id)
+			cycles = append(cycles, append(slices.Clone(path[start:]), id))
+			return
+		case done:
+			return
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		for _, next := range reports[id] {
+			visit(next, path)
+		}
+		state[id] = done
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(reports)) {
+		if state[id] == unvisited {
+			visit(id, nil)
+		}
+	}
+	return cycles
+}
+
+func findOrphans(cfg Config) []string {
+	referenced := map[string]bool{}
+	for _, relation := range cfg.Relations {
+		referenced[relation.Manager] = true
+		for _, report := range relation.Report {
+			referenced[report] = true
+		}
+	}
+	for _, id := range getGroupPositions(cfg) {
+		referenced[id] = true
+	}
+
+	var orphans []string
+	for _, id := range slices.Sorted(maps.Keys(cfg.People)) {
+		if !referenced[id] {
+			orphans = append(orphans, id)
+		}
+	}
+	return orphans
+}
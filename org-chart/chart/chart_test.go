@@ -0,0 +1,70 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: chart_test.go
+Description: Tests for Lint's cycle and orphan detection
+Author: tengzl33t
+*/
+
+package chart
+
+import "testing"
+
+func TestLintFindCycles(t *testing.T) {
+	cfg := Config{
+		People: map[string]Person{
+			"a": {Title: "A"},
+			"b": {Title: "B"},
+			"c": {Title: "C"},
+		},
+		Relations: []Relation{
+			{Manager: "a", Report: []string{"b"}},
+			{Manager: "b", Report: []string{"c"}},
+			{Manager: "c", Report: []string{"a"}},
+		},
+	}
+
+	cycles := findCycles(cfg)
+	if len(cycles) == 0 {
+		t.Fatalf("expected at least one cycle, got none")
+	}
+}
+
+func TestLintNoFalseCycle(t *testing.T) {
+	cfg := Config{
+		People: map[string]Person{
+			"a": {Title: "A"},
+			"b": {Title: "B"},
+			"c": {Title: "C"},
+		},
+		Relations: []Relation{
+			{Manager: "a", Report: []string{"b", "c"}},
+		},
+	}
+
+	if cycles := findCycles(cfg); len(cycles) != 0 {
+		t.Fatalf("expected no cycles in a tree, got %v", cycles)
+	}
+}
+
+func TestLintFindOrphans(t *testing.T) {
+	cfg := Config{
+		People: map[string]Person{
+			"a": {Title: "A"},
+			"b": {Title: "B"},
+			"c": {Title: "C"},
+		},
+		Relations: []Relation{
+			{Manager: "a", Report: []string{"b"}},
+		},
+	}
+
+	orphans := findOrphans(cfg)
+	if len(orphans) != 1 || orphans[0] != "c" {
+		t.Fatalf("expected orphans [c], got %v", orphans)
+	}
+}
@@ -6,209 +6,110 @@ file, You can obtain one at https://mozilla.org/MPL/2.0/.
 SPDX-License-Identifier: MPL-2.0
 
 File: org-chart.go
-Description: Organization chart generator
+Description: Organization chart generator (CLI)
 Author: tengzl33t
 */
 
 package main
 
 import (
+	"bytes"
 	"context"
-	"github.com/goccy/go-graphviz"
-	"github.com/goccy/go-graphviz/cgraph"
-	"gopkg.in/yaml.v3"
-	"maps"
+	"flag"
+	"io"
 	"os"
-	"slices"
-)
-
-type Person struct {
-	Title    string `yaml:"title"`
-	Position string `yaml:"position"`
-}
-
-type Group struct {
-	Title     string   `yaml:"title"`
-	Positions []string `yaml:"positions"`
-}
-
-type Relation struct {
-	Manager string   `yaml:"manager"`
-	Report  []string `yaml:"report"`
-}
-
-type Config struct {
-	People    map[string]Person `yaml:"people"`
-	Groups    map[string]Group  `yaml:"groups"`
-	Relations []Relation        `yaml:"relations"`
-}
-
-type NodeInfo struct {
-	Node        *cgraph.Node
-	IsCluster   bool
-	ClusterName string
-	RepNode     *cgraph.Node
-}
-
-func createEdges(cfg Config, nodes map[string]*NodeInfo, graph *cgraph.Graph) {
-	for _, relation := range cfg.Relations {
-		if len(relation.Report) == 0 {
-			println("Warning: Relation of " + relation.Manager + " has no report entries")
-			continue
-		}
-		fromInfo := nodes[relation.Manager]
-		for _, report := range relation.Report {
-			toInfo := nodes[report]
-
-			if fromInfo == nil || toInfo == nil {
-				println("Warning: Missing node for relation " + relation.Manager + "-> " + report)
-				continue
-			}
-
-			if fromInfo.RepNode == nil || toInfo.RepNode == nil {
-				println("Warning: Missing representative node for relation " + relation.Manager + "-> " + report)
-				continue
-			}
+	"time"
 
-			edge, err := graph.CreateEdgeByName("", fromInfo.RepNode, toInfo.RepNode)
-			if err != nil {
-				println("Error creating edge " + err.Error())
-				continue
-			}
+	"github.com/goccy/go-graphviz"
 
-			if fromInfo.IsCluster {
-				edge.SetLogicalTail(fromInfo.ClusterName)
-			}
-			if toInfo.IsCluster {
-				edge.SetLogicalHead(toInfo.ClusterName)
-			}
+	"tengzl33t/smallgoes/org-chart/chart"
+)
 
-			edge.SetColor("#666666")
-			edge.SetMinLen(2)
-		}
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
 	}
+	return os.Open(path)
 }
 
-func createPerson(id string, person Person, graph *cgraph.Graph, nodes map[string]*NodeInfo) *cgraph.Node {
-	item, _ := graph.CreateNodeByName(id)
-	label := person.Title + "\\n" + person.Position
-	item.SetLabel(label)
-	item.SetShape("box")
-	item.SetStyle("rounded,filled")
-	item.SetFillColor("#E8F0FE")
-	nodes[id] = &NodeInfo{
-		Node:      item,
-		IsCluster: false,
-		RepNode:   item,
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
 	}
-	return item
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 }
 
-func createGroups(cfg Config, nodes map[string]*NodeInfo, graph *cgraph.Graph) {
-	for _, id := range slices.Sorted(maps.Keys(cfg.Groups)) {
-		group := cfg.Groups[id]
-		if len(group.Positions) == 0 {
-			println("Warning: Group '" + id + "' has no positions")
-			continue
-		}
-
-		clusterName := "cluster_" + id
-		cluster, _ := graph.CreateSubGraphByName(clusterName)
-		cluster.SetLabel(group.Title)
-		cluster.SetStyle("rounded,filled")
-		cluster.SetBackgroundColor("#AAAAAA")
+type nopWriteCloser struct{ io.Writer }
 
-		var posNodes []*cgraph.Node
+func (nopWriteCloser) Close() error { return nil }
 
-		for _, position := range group.Positions {
-			posNodes = append(posNodes, createPerson(position, cfg.People[position], cluster, nodes))
-		}
-
-		var repNode *cgraph.Node
-		if len(posNodes) > 0 {
-			middleIndex := len(posNodes) / 2
-			repNode = posNodes[middleIndex]
-		}
-
-		nodes[id] = &NodeInfo{
-			Node:        nil,
-			IsCluster:   true,
-			ClusterName: clusterName,
-			RepNode:     repNode,
-		}
+func renderOnce(ctx context.Context, gv *graphviz.Graphviz, input, output string, opts chart.RenderOptions) error {
+	in, err := openInput(input)
+	if err != nil {
+		return err
 	}
-}
-
-func getGroupPositions(cfg Config) []string {
-	var positions []string
-	for _, v := range cfg.Groups {
-		positions = append(positions, v.Positions...)
+	cfg, err := chart.LoadConfig(in)
+	_ = in.Close()
+	if err != nil {
+		return err
 	}
-	return positions
-}
 
-func createPeople(cfg Config, nodes map[string]*NodeInfo, graph *cgraph.Graph) {
-	gropedPositions := getGroupPositions(cfg)
+	for _, warning := range chart.Lint(cfg) {
+		println(warning)
+	}
 
-	for _, id := range slices.Sorted(maps.Keys(cfg.People)) {
-		if !slices.Contains(gropedPositions, id) {
-			createPerson(id, cfg.People[id], graph, nodes)
-		}
+	var buf bytes.Buffer
+	if err := chart.Render(ctx, gv, cfg, opts, &buf); err != nil {
+		return err
 	}
-}
 
-func renderGraph(
-	gv *graphviz.Graphviz,
-	cfg Config,
-) {
-	ctx := context.Background()
-	graph, err := gv.Graph()
+	out, err := openOutput(output)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer out.Close()
 
-	defer func(graph *graphviz.Graph) {
-		err := graph.Close()
-		if err != nil {
-			panic(err)
-		}
-	}(graph)
-
-	graph.SetRankDir("TB")
-	graph.SetCompound(true)
-
-	nodes := map[string]*NodeInfo{}
-
-	createPeople(cfg, nodes, graph)
-	createGroups(cfg, nodes, graph)
-	createEdges(cfg, nodes, graph)
+	_, err = out.Write(buf.Bytes())
+	return err
+}
 
-	file, err := os.OpenFile("file.svg", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	if err != nil {
-		panic(err)
-	}
-	defer func(file *os.File) {
-		err := file.Close()
+// watch re-renders whenever the input file's mtime changes, polling
+// rather than pulling in a filesystem-notification dependency for what
+// is otherwise a small CLI.
+func watch(ctx context.Context, gv *graphviz.Graphviz, input, output string, opts chart.RenderOptions) error {
+	var lastMod time.Time
+	for {
+		info, err := os.Stat(input)
 		if err != nil {
-			panic(err)
+			return err
 		}
-	}(file)
-
-	if err := gv.Render(ctx, graph, graphviz.SVG, file); err != nil {
-		panic(err)
+		if info.ModTime().After(lastMod) {
+			lastMod = info.ModTime()
+			if err := renderOnce(ctx, gv, input, output, opts); err != nil {
+				println("Error rendering: " + err.Error())
+			} else {
+				println("Re-rendered " + output + " from " + input)
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
 	}
-	println("Graph rendered successfully to file.svg")
 }
 
 func main() {
-	data, err := os.ReadFile("org.yaml")
-	if err != nil {
-		panic(err)
-	}
-
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		panic(err)
+	input := flag.String("input", "org.yaml", "input YAML file, or - for stdin")
+	output := flag.String("output", "file.svg", "output file, or - for stdout")
+	format := flag.String("format", "svg", "output format: svg|png|pdf|dot|json")
+	layout := flag.String("layout", "dot", "graphviz layout engine: dot|neato|fdp|circo")
+	rankdir := flag.String("rankdir", "TB", "rank direction: TB|LR")
+	watchMode := flag.Bool("watch", false, "re-render whenever the input YAML changes")
+	legend := flag.Bool("legend", false, "render a legend explaining line styles")
+	flag.Parse()
+
+	opts := chart.RenderOptions{
+		Format:  chart.Format(*format),
+		Layout:  chart.Layout(*layout),
+		RankDir: chart.RankDir(*rankdir),
+		Legend:  *legend,
 	}
 
 	ctx := context.Background()
@@ -216,13 +117,28 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	defer func() {
+		if err := gv.Close(); err != nil {
+			panic(err)
+		}
+	}()
 
-	defer func(g *graphviz.Graphviz) {
-		err := g.Close()
-		if err != nil {
+	if *watchMode {
+		if *input == "-" {
+			println("--watch can't be used with stdin input")
+			os.Exit(1)
+		}
+		if err := watch(ctx, gv, *input, *output, opts); err != nil {
 			panic(err)
 		}
-	}(gv)
+		return
+	}
 
-	renderGraph(gv, cfg)
+	if err := renderOnce(ctx, gv, *input, *output, opts); err != nil {
+		panic(err)
+	}
+
+	if *output != "-" {
+		println("Graph rendered successfully to " + *output)
+	}
 }
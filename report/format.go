@@ -0,0 +1,211 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: format.go
+Description: text/json/sarif/github output modes for Report
+Author: tengzl33t
+*/
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Format selects one of the supported --format values.
+type Format string
+
+const (
+	FormatText   Format = "text"
+	FormatJSON   Format = "json"
+	FormatSARIF  Format = "sarif"
+	FormatGitHub Format = "github"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatText, FormatJSON, FormatSARIF, FormatGitHub:
+		return Format(s), nil
+	default:
+		return "", fmt.Errorf("unknown format %q: must be one of text, json, sarif, github", s)
+	}
+}
+
+// ParseFormatFlag pulls the first "--format=..." argument out of args,
+// defaulting to FormatText when absent. Both validator CLIs share this so
+// "--format" behaves identically across them.
+func ParseFormatFlag(args []string) (Format, []string, error) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--format=") {
+			format, err := ParseFormat(strings.TrimPrefix(arg, "--format="))
+			if err != nil {
+				return "", nil, err
+			}
+			rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return format, rest, nil
+		}
+	}
+	return FormatText, args, nil
+}
+
+// Render writes reports in the requested format. toolName identifies the
+// validator in SARIF output (e.g. "txc-validator").
+func Render(format Format, toolName string, reports []Report) (string, error) {
+	switch format {
+	case FormatJSON:
+		return renderJSON(reports)
+	case FormatSARIF:
+		return renderSARIF(toolName, reports)
+	case FormatGitHub:
+		return renderGitHub(reports), nil
+	case FormatText, "":
+		return renderText(reports), nil
+	default:
+		return "", fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func renderText(reports []Report) string {
+	var b strings.Builder
+	for _, r := range reports {
+		if len(r.Diagnostics) == 0 {
+			fmt.Fprintf(&b, "Config file %s validation succeeded.\n", r.File)
+			continue
+		}
+		status := "failed"
+		if !r.HasErrors() {
+			status = "succeeded with warnings"
+		}
+		fmt.Fprintf(&b, "Config file '%s' validation %s.\nIssues:\n", r.File, status)
+		for _, d := range r.Diagnostics {
+			fmt.Fprintf(&b, "- [%s] %s (%s): %s\n", d.Severity, d.Path, d.RuleID, d.Message)
+		}
+	}
+	return b.String()
+}
+
+func renderJSON(reports []Report) (string, error) {
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func renderGitHub(reports []Report) string {
+	var b strings.Builder
+	for _, r := range reports {
+		for _, d := range r.Diagnostics {
+			cmd := "error"
+			if d.Severity == SeverityWarning {
+				cmd = "warning"
+			}
+			fmt.Fprintf(&b, "::%s file=%s::%s (%s) at %s\n", cmd, r.File, d.Message, d.RuleID, d.Path)
+		}
+	}
+	return b.String()
+}
+
+// sarifLog and friends are a minimal subset of the SARIF 2.1.0 schema,
+// enough for GitHub code scanning to ingest our results.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func sarifLevel(s Severity) string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+func renderSARIF(toolName string, reports []Report) (string, error) {
+	ruleSeen := map[string]bool{}
+	var rules []sarifRule
+	var results []sarifResult
+
+	for _, r := range reports {
+		for _, d := range r.Diagnostics {
+			if !ruleSeen[d.RuleID] {
+				ruleSeen[d.RuleID] = true
+				rules = append(rules, sarifRule{ID: d.RuleID})
+			}
+			results = append(results, sarifResult{
+				RuleID:  d.RuleID,
+				Level:   sarifLevel(d.Severity),
+				Message: sarifMessage{Text: d.Message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: r.File},
+					},
+				}},
+				Properties: map[string]string{"path": d.Path},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: toolName, Rules: rules}},
+			Results: results,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
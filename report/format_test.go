@@ -0,0 +1,47 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: format_test.go
+Description: Tests for the shared --format flag parsing used by both CLIs
+Author: tengzl33t
+*/
+
+package report
+
+import "testing"
+
+func TestParseFormatFlagDefault(t *testing.T) {
+	format, rest, err := ParseFormatFlag([]string{"config.json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if format != FormatText {
+		t.Fatalf("got %v, want %v", format, FormatText)
+	}
+	if len(rest) != 1 || rest[0] != "config.json" {
+		t.Fatalf("got %v, want [config.json]", rest)
+	}
+}
+
+func TestParseFormatFlagPresent(t *testing.T) {
+	format, rest, err := ParseFormatFlag([]string{"--format=sarif", "config.json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if format != FormatSARIF {
+		t.Fatalf("got %v, want %v", format, FormatSARIF)
+	}
+	if len(rest) != 1 || rest[0] != "config.json" {
+		t.Fatalf("got %v, want [config.json]", rest)
+	}
+}
+
+func TestParseFormatFlagInvalid(t *testing.T) {
+	if _, _, err := ParseFormatFlag([]string{"--format=yaml"}); err == nil {
+		t.Fatalf("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,67 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: report.go
+Description: Structured diagnostics shared by the TXReports and
+TXCertbot config validators
+Author: tengzl33t
+*/
+
+package report
+
+// Severity classifies a Diagnostic. Warnings don't fail validation on
+// their own; errors do.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is one validation finding, located by a JSON Pointer
+// (RFC 6901) into the document that was validated.
+type Diagnostic struct {
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	Path     string   `json:"path"`
+	Message  string   `json:"message"`
+}
+
+// Report collects every Diagnostic produced while validating one file.
+type Report struct {
+	File        string       `json:"file"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+func (r *Report) Errorf(path, ruleID, message string) {
+	r.add(SeverityError, path, ruleID, message)
+}
+
+func (r *Report) Warnf(path, ruleID, message string) {
+	r.add(SeverityWarning, path, ruleID, message)
+}
+
+func (r *Report) add(severity Severity, path, ruleID, message string) {
+	r.Diagnostics = append(r.Diagnostics, Diagnostic{
+		RuleID:   ruleID,
+		Severity: severity,
+		Path:     path,
+		Message:  message,
+	})
+}
+
+// HasErrors reports whether any Diagnostic in r is an error, which is
+// what callers should treat as validation failure; warnings alone don't
+// fail a run.
+func (r Report) HasErrors() bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: schema.go
+Description: Embeds the JSON Schema documents so the validator CLIs can
+dump them for editor autocomplete/inline validation via --schema.
+Author: tengzl33t
+*/
+
+package schema
+
+import _ "embed"
+
+//go:embed txcertbot.schema.json
+var TXCertbot []byte
+
+//go:embed txreports.schema.json
+var TXReports []byte
@@ -0,0 +1,370 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: acme.go
+Description: ACME v2 provisioning for TXCertbot site groups
+Author: tengzl33t
+*/
+
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// Mode mirrors the cert_mode values accepted by the TXCertbot config:
+// "san" issues a single multi-domain cert for the whole site group,
+// "classic" issues one cert per site.
+type Mode string
+
+const (
+	ModeSAN     Mode = "san"
+	ModeClassic Mode = "classic"
+)
+
+// KeyType mirrors the cert_type values accepted by the TXCertbot config.
+type KeyType string
+
+const (
+	KeyECP256  KeyType = "ec-256"
+	KeyECP384  KeyType = "ec-384"
+	KeyRSA2048 KeyType = "2048"
+	KeyRSA3072 KeyType = "3072"
+	KeyRSA4096 KeyType = "4096"
+)
+
+// directoryURLs maps the provider names from validator.AllowedCertProviders to
+// their ACME v2 directory endpoint. The "_test" suffixed providers route
+// to the provider's staging environment instead of production.
+var directoryURLs = map[string]string{
+	"letsencrypt":      "https://acme-v02.api.letsencrypt.org/directory",
+	"letsencrypt_test": "https://acme-staging-v02.api.letsencrypt.org/directory",
+	"buypass":          "https://api.buypass.com/acme/directory",
+	"buypass_test":     "https://api.test4.buypass.no/acme/directory",
+	"zerossl":          "https://acme.zerossl.com/v2/DV90",
+	"google":           "https://dv.acme-v02.api.pki.goog/directory",
+	"google_test":      "https://dv.acme-v02.test-api.pki.goog/directory",
+	"sslcom":           "https://acme.ssl.com/sslcom-dv-rsa",
+}
+
+// EABCredentials carries the external account binding material required
+// by providers that demand it (Google Trust Services, SSL.com, ZeroSSL).
+type EABCredentials struct {
+	Email   string
+	KID     string
+	HMACKey string
+}
+
+// Request describes one site group's worth of certificate work, built
+// from the validated tenantStruct/siteGroupStruct pair.
+type Request struct {
+	Tenant      string
+	Env         string
+	GroupName   string
+	Sites       []string
+	Mode        Mode
+	Provider    string
+	KeyType     KeyType
+	Credentials *EABCredentials
+}
+
+// DNSProvider creates and tears down the TXT record needed to satisfy a
+// dns-01 challenge. Provisioner validates every domain via dns-01, the
+// only challenge type that can validate wildcard site groups (matching
+// getCertModeRegex()), so provisioning fails if none is configured. Implementations
+// are provider-specific and supplied by the caller, the same way
+// cert_provider allow-lists are supplied by the validator.
+type DNSProvider interface {
+	Present(ctx context.Context, domain, keyAuth string) error
+	CleanUp(ctx context.Context, domain, keyAuth string) error
+}
+
+// Record is the persisted result of a successful issuance.
+type Record struct {
+	Tenant      string
+	Env         string
+	GroupName   string
+	Domains     []string
+	PrivateKey  []byte // PEM
+	Certificate []byte // PEM, full chain
+	NotAfter    time.Time
+}
+
+// Store persists ACME account keys and issued certificates keyed by
+// tenant+env, mirroring how the validator keys everything off tenant/env.
+type Store interface {
+	LoadAccountKey(tenant, env, provider string) (crypto.Signer, bool, error)
+	SaveAccountKey(tenant, env, provider string, key crypto.Signer) error
+	SaveRecord(rec Record) error
+	LoadRecord(tenant, env, groupName string) (Record, bool, error)
+}
+
+// Provisioner drives certificate issuance and renewal against the ACME
+// providers accepted by the validator.
+type Provisioner struct {
+	Store Store
+	DNS   DNSProvider
+}
+
+func NewProvisioner(store Store, dns DNSProvider) *Provisioner {
+	return &Provisioner{Store: store, DNS: dns}
+}
+
+func directoryURL(provider string) (string, error) {
+	url, ok := directoryURLs[provider]
+	if !ok {
+		return "", fmt.Errorf("no ACME directory known for provider %q", provider)
+	}
+	return url, nil
+}
+
+// SupportsStaging reports whether provider has a "_test" staging directory
+// entry. Not every provider in directoryURLs does (zerossl and sslcom
+// don't), so callers routing a provider to staging should check this first
+// rather than letting an unknown-directory error surface from deep inside
+// Provision.
+func SupportsStaging(provider string) bool {
+	_, ok := directoryURLs[provider+"_test"]
+	return ok
+}
+
+func generateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KeyECP256, "":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyECP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case KeyRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyRSA3072:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case KeyRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	default:
+		return nil, fmt.Errorf("unsupported cert_type %q", keyType)
+	}
+}
+
+func isWildcard(domain string) bool {
+	return strings.HasPrefix(domain, "*.")
+}
+
+// domainGroups splits a site group's sites into the cert bundles the
+// Mode calls for: one bundle for "san", one bundle per site for
+// "classic".
+func domainGroups(req Request) [][]string {
+	if req.Mode == ModeClassic {
+		groups := make([][]string, 0, len(req.Sites))
+		for _, site := range req.Sites {
+			groups = append(groups, []string{site})
+		}
+		return groups
+	}
+	return [][]string{req.Sites}
+}
+
+func (p *Provisioner) account(ctx context.Context, client *acme.Client, req Request) (crypto.Signer, error) {
+	key, found, err := p.Store.LoadAccountKey(req.Tenant, req.Env, req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		key, err = generateKey(KeyECP256)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.Store.SaveAccountKey(req.Tenant, req.Env, req.Provider, key); err != nil {
+			return nil, err
+		}
+	}
+	client.Key = key
+
+	account := &acme.Account{Contact: []string{"mailto:" + contactEmail(req)}}
+	if req.Credentials != nil {
+		account.ExternalAccountBinding = &acme.ExternalAccountBinding{
+			KID: req.Credentials.KID,
+			Key: []byte(req.Credentials.HMACKey),
+		}
+	}
+
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("registering ACME account for %s/%s: %w", req.Tenant, req.Env, err)
+	}
+
+	return key, nil
+}
+
+func contactEmail(req Request) string {
+	if req.Credentials != nil && req.Credentials.Email != "" {
+		return req.Credentials.Email
+	}
+	return req.Tenant + "@" + req.Env + ".invalid"
+}
+
+// authorizeDomain walks one already-fetched ACME authorization through the
+// dns-01 challenge, which is the only challenge type capable of validating
+// wildcard domains. The domain comes from authz.Identifier.Value rather
+// than the submitted domain list, since RFC 8555 doesn't guarantee
+// order.AuthzURLs lines up positionally with the domains an order was
+// created for.
+func (p *Provisioner) authorizeDomain(ctx context.Context, client *acme.Client, authzURL string, authz *acme.Authorization) error {
+	domain := authz.Identifier.Value
+
+	if p.DNS == nil {
+		return fmt.Errorf("domain %q requires dns-01 but no DNSProvider is configured", domain)
+	}
+
+	var chal *acme.Challenge
+	for _, c := range authz.Challenges {
+		if c.Type == "dns-01" {
+			chal = c
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("no dns-01 challenge offered for %q", domain)
+	}
+
+	keyAuth, err := client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return err
+	}
+
+	if err := p.DNS.Present(ctx, domain, keyAuth); err != nil {
+		return fmt.Errorf("presenting dns-01 record for %q: %w", domain, err)
+	}
+	defer func() { _ = p.DNS.CleanUp(ctx, domain, keyAuth) }()
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("accepting dns-01 challenge for %q: %w", domain, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authzURL); err != nil {
+		return fmt.Errorf("waiting on authorization for %q: %w", domain, err)
+	}
+	return nil
+}
+
+func (p *Provisioner) issueBundle(ctx context.Context, client *acme.Client, req Request, domains []string) error {
+	certKey, err := generateKey(req.KeyType)
+	if err != nil {
+		return err
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return fmt.Errorf("creating order for %v: %w", domains, err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return fmt.Errorf("fetching authorization %q: %w", authzURL, err)
+		}
+		if err := p.authorizeDomain(ctx, client, authzURL, authz); err != nil {
+			return err
+		}
+	}
+
+	csr, err := certRequest(certKey, domains)
+	if err != nil {
+		return err
+	}
+
+	der, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return fmt.Errorf("finalizing order for %v: %w", domains, err)
+	}
+
+	keyPEM, err := marshalKey(certKey)
+	if err != nil {
+		return err
+	}
+
+	cert, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return err
+	}
+
+	var chainPEM []byte
+	for _, b := range der {
+		chainPEM = append(chainPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+
+	return p.Store.SaveRecord(Record{
+		Tenant:      req.Tenant,
+		Env:         req.Env,
+		GroupName:   req.GroupName,
+		Domains:     domains,
+		PrivateKey:  keyPEM,
+		Certificate: chainPEM,
+		NotAfter:    cert.NotAfter,
+	})
+}
+
+// Provision issues fresh certificates for every bundle implied by
+// req.Mode, registering (or reusing) the tenant's ACME account first.
+func (p *Provisioner) Provision(ctx context.Context, req Request) error {
+	dirURL, err := directoryURL(req.Provider)
+	if err != nil {
+		return err
+	}
+	client := &acme.Client{DirectoryURL: dirURL}
+
+	if _, err := p.account(ctx, client, req); err != nil {
+		return err
+	}
+
+	for _, domains := range domainGroups(req) {
+		if err := p.issueBundle(ctx, client, req, domains); err != nil {
+			return fmt.Errorf("site group %q: %w", req.GroupName, err)
+		}
+	}
+	return nil
+}
+
+// Renew re-issues a site group's certificate if the persisted record is
+// missing or within 30 days of expiry; otherwise it's a no-op.
+func (p *Provisioner) Renew(ctx context.Context, req Request) error {
+	rec, found, err := p.Store.LoadRecord(req.Tenant, req.Env, req.GroupName)
+	if err != nil {
+		return err
+	}
+	if found && time.Until(rec.NotAfter) > 30*24*time.Hour {
+		return nil
+	}
+	return p.Provision(ctx, req)
+}
+
+func certRequest(key crypto.Signer, domains []string) ([]byte, error) {
+	tmpl := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}
+	return x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+}
+
+func marshalKey(key crypto.Signer) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,71 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: acme_test.go
+Description: Tests for the pure helpers around request/domain handling
+Author: tengzl33t
+*/
+
+package acme
+
+import "testing"
+
+func TestIsWildcard(t *testing.T) {
+	cases := map[string]bool{
+		"*.example.com":   true,
+		"www.example.com": false,
+		"example.com":     false,
+	}
+	for domain, want := range cases {
+		if got := isWildcard(domain); got != want {
+			t.Errorf("isWildcard(%q) = %v, want %v", domain, got, want)
+		}
+	}
+}
+
+func TestDomainGroupsSAN(t *testing.T) {
+	req := Request{Mode: ModeSAN, Sites: []string{"a.example.com", "b.example.com"}}
+	groups := domainGroups(req)
+	if len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("expected a single bundle of 2 domains, got %v", groups)
+	}
+}
+
+func TestDomainGroupsClassic(t *testing.T) {
+	req := Request{Mode: ModeClassic, Sites: []string{"a.example.com", "b.example.com"}}
+	groups := domainGroups(req)
+	if len(groups) != 2 || len(groups[0]) != 1 || len(groups[1]) != 1 {
+		t.Fatalf("expected one bundle per site, got %v", groups)
+	}
+}
+
+func TestSupportsStaging(t *testing.T) {
+	cases := map[string]bool{
+		"letsencrypt": true,
+		"buypass":     true,
+		"google":      true,
+		"zerossl":     false,
+		"sslcom":      false,
+	}
+	for provider, want := range cases {
+		if got := SupportsStaging(provider); got != want {
+			t.Errorf("SupportsStaging(%q) = %v, want %v", provider, got, want)
+		}
+	}
+}
+
+func TestContactEmail(t *testing.T) {
+	req := Request{Tenant: "acme-corp", Env: "prod"}
+	if got := contactEmail(req); got != "acme-corp@prod.invalid" {
+		t.Fatalf("got %q, want acme-corp@prod.invalid", got)
+	}
+
+	req.Credentials = &EABCredentials{Email: "ops@acme-corp.example"}
+	if got := contactEmail(req); got != "ops@acme-corp.example" {
+		t.Fatalf("got %q, want ops@acme-corp.example", got)
+	}
+}
@@ -0,0 +1,60 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: exec_dns.go
+Description: DNSProvider backed by operator-supplied hook scripts
+Author: tengzl33t
+*/
+
+package acme
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ExecDNSProvider implements DNSProvider by shelling out to operator-
+// supplied scripts, mirroring certbot's --manual-auth-hook /
+// --manual-cleanup-hook convention. Each script is invoked with
+// CERTBOT_DOMAIN and CERTBOT_VALIDATION set in its environment; it's
+// responsible for creating (AuthHook) or removing (CleanupHook) the
+// "_acme-challenge.<domain>" TXT record at the DNS provider of the
+// operator's choice.
+type ExecDNSProvider struct {
+	AuthHook    string
+	CleanupHook string
+}
+
+func (e *ExecDNSProvider) Present(ctx context.Context, domain, keyAuth string) error {
+	if e.AuthHook == "" {
+		return fmt.Errorf("no DNS auth hook configured for domain %q", domain)
+	}
+	return run(ctx, e.AuthHook, domain, keyAuth)
+}
+
+func (e *ExecDNSProvider) CleanUp(ctx context.Context, domain, keyAuth string) error {
+	if e.CleanupHook == "" {
+		return nil
+	}
+	return run(ctx, e.CleanupHook, domain, keyAuth)
+}
+
+func run(ctx context.Context, script, domain, keyAuth string) error {
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Env = append(os.Environ(),
+		"CERTBOT_DOMAIN="+domain,
+		"CERTBOT_VALIDATION="+keyAuth,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running DNS hook %q for %q: %w", script, domain, err)
+	}
+	return nil
+}
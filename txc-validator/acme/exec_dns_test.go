@@ -0,0 +1,62 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: exec_dns_test.go
+Description: Tests for the hook-script-backed DNSProvider
+Author: tengzl33t
+*/
+
+package acme
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeHookScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("writing hook script: %v", err)
+	}
+	return path
+}
+
+func TestExecDNSProviderPresentRunsHookWithEnv(t *testing.T) {
+	dir := t.TempDir()
+	outFile := filepath.Join(dir, "out.txt")
+	hook := writeHookScript(t, dir, "auth.sh", `echo "$CERTBOT_DOMAIN $CERTBOT_VALIDATION" > `+outFile)
+
+	provider := &ExecDNSProvider{AuthHook: hook}
+	if err := provider.Present(context.Background(), "example.com", "token123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("reading hook output: %v", err)
+	}
+	if got := string(data); got != "example.com token123\n" {
+		t.Fatalf("got %q, want %q", got, "example.com token123\n")
+	}
+}
+
+func TestExecDNSProviderPresentWithoutHookFails(t *testing.T) {
+	provider := &ExecDNSProvider{}
+	if err := provider.Present(context.Background(), "example.com", "token123"); err == nil {
+		t.Fatalf("expected an error when no auth hook is configured")
+	}
+}
+
+func TestExecDNSProviderCleanUpWithoutHookIsNoop(t *testing.T) {
+	provider := &ExecDNSProvider{}
+	if err := provider.CleanUp(context.Background(), "example.com", "token123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: store.go
+Description: Filesystem-backed Store for ACME account keys and certs
+Author: tengzl33t
+*/
+
+package acme
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps one account key per tenant+env+provider and one record
+// per tenant+env+group under BaseDir, e.g. <BaseDir>/<tenant>/<env>/<provider>.key and
+// <BaseDir>/<tenant>/<env>/<group>.json.
+type FileStore struct {
+	BaseDir string
+}
+
+func (s *FileStore) accountKeyPath(tenant, env, provider string) string {
+	return filepath.Join(s.BaseDir, tenant, env, provider+".key")
+}
+
+func (s *FileStore) recordPath(tenant, env, groupName string) string {
+	return filepath.Join(s.BaseDir, tenant, env, groupName+".json")
+}
+
+func (s *FileStore) LoadAccountKey(tenant, env, provider string) (crypto.Signer, bool, error) {
+	der, err := os.ReadFile(s.accountKeyPath(tenant, env, provider))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, false, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, false, errors.New("stored account key does not implement crypto.Signer")
+	}
+	return signer, true, nil
+}
+
+func (s *FileStore) SaveAccountKey(tenant, env, provider string, key crypto.Signer) error {
+	path := s.accountKeyPath(tenant, env, provider)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, der, 0600)
+}
+
+func (s *FileStore) SaveRecord(rec Record) error {
+	path := s.recordPath(rec.Tenant, rec.Env, rec.GroupName)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (s *FileStore) LoadRecord(tenant, env, groupName string) (Record, bool, error) {
+	data, err := os.ReadFile(s.recordPath(tenant, env, groupName))
+	if errors.Is(err, os.ErrNotExist) {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, err
+	}
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Record{}, false, err
+	}
+	return rec, true, nil
+}
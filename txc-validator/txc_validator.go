@@ -6,7 +6,7 @@ file, You can obtain one at https://mozilla.org/MPL/2.0/.
 SPDX-License-Identifier: MPL-2.0
 
 File: txc_validator.go
-Description: TXCertbot 2.X compact Go config validator
+Description: TXCertbot 2.X compact Go config validator (CLI)
 Author: tengzl33t
 
 Better to compile with tinygo:
@@ -16,267 +16,205 @@ GOTOOLCHAIN=go1.21.6 GOSUMDB='sum.golang.org' tinygo build -scheduler=none -pani
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
-	"slices"
 	"strings"
-)
-
-type StringSet map[string]struct{}
 
-type siteGroupStruct struct {
-	GroupName               string          `json:"group_name"`
-	Sites                   []string        `json:"sites"`
-	CertMode                string          `json:"cert_mode"`
-	CertProvider            string          `json:"cert_provider"`
-	CertType                string          `json:"cert_type"`
-	CertProviderCredentials *EABCredentials `json:"cert_provider_creds"`
-}
-
-type tenantStruct struct {
-	Tenant     string            `json:"tenant"`
-	Env        string            `json:"env"`
-	SiteGroups []siteGroupStruct `json:"site_groups"`
-}
+	"tengzl33t/smallgoes/report"
+	"tengzl33t/smallgoes/schema"
+	"tengzl33t/smallgoes/txc-validator/acme"
+	"tengzl33t/smallgoes/txc-validator/validator"
+)
 
-type EABCredentials struct {
-	Email   string `json:"email"`
-	KID     string `json:"kid"`
-	HMACKey string `json:"hmac_key"`
-}
+// acmeStoreDir is where ACME account keys and issued certs are persisted,
+// keyed by tenant+env underneath it.
+const acmeStoreDir = ".txcertbot/acme"
 
-func getAllowedProviders() []string {
-	return []string{
-		"letsencrypt",
-		"buypass",
-		"zerossl",
-		"sslcom",
-		"google",
-		"google_test",
-		"buypass_test",
-		"letsencrypt_test",
+func getFileOrDir(path string) []string {
+	file, err := os.Stat(path)
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
 	}
-}
+	switch {
+	case file.Mode().IsDir():
+		if path[len(path)-1] != '/' {
+			path = path + "/"
+		}
+		jsonFiles, _ := filepath.Glob(path + "*.json")
+		return jsonFiles
+	case file.Mode().IsRegular():
+		fileExt := path[strings.LastIndex(path, ".")+1:]
 
-func getAllowedCertTypes() []string {
-	return []string{
-		"ec-256",
-		"ec-384",
-		"2048",
-		"3072",
-		"4096",
+		if fileExt != "json" {
+			println(err)
+			os.Exit(1)
+		}
+		return []string{path}
+	default:
+		return []string{}
 	}
 }
 
-func getAllowedCertModes() []string {
-	return []string{
-		"san",
-		"classic",
+// stagingProvider routes a provider to its "_test" staging endpoint when
+// --staging is passed, so operators can rehearse a renewal run without
+// touching rate limits on the production ACME directory. Not every
+// provider has a staging directory (see acme.SupportsStaging), so this
+// fails clearly up front instead of letting an opaque "no ACME directory
+// known" error surface from deep inside Provision.
+func stagingProvider(provider string, staging bool) (string, error) {
+	if !staging || strings.HasSuffix(provider, "_test") {
+		return provider, nil
 	}
+	if !acme.SupportsStaging(provider) {
+		return "", fmt.Errorf("cert_provider %q has no staging directory; rerun without --staging or use a different provider", provider)
+	}
+	return provider + "_test", nil
 }
 
-func getCertModeRegex() *regexp.Regexp {
-	reg, _ := regexp.Compile("^\\*\\.\\S+\\.\\w+$")
-	return reg
-}
+func buildAcmeRequests(tenants []validator.Tenant, staging bool) ([]acme.Request, error) {
+	var requests []acme.Request
+	for _, t := range tenants {
+		for _, sg := range t.SiteGroups {
+			mode := acme.ModeSAN
+			if sg.CertMode == "classic" {
+				mode = acme.ModeClassic
+			}
 
-func getCertModeSiteRegex(certMode string) *regexp.Regexp {
-	fixedMode := strings.ReplaceAll(certMode[2:], ".", "\\.")
-	reg, _ := regexp.Compile("^([a-zA-Z0-9-]+\\.)?" + fixedMode + "$")
-	return reg
-}
+			var creds *acme.EABCredentials
+			if sg.CertProviderCredentials != nil {
+				creds = &acme.EABCredentials{
+					Email:   sg.CertProviderCredentials.Email,
+					KID:     sg.CertProviderCredentials.KID,
+					HMACKey: sg.CertProviderCredentials.HMACKey,
+				}
+			}
 
-func getSimpleSiteRegex() *regexp.Regexp {
-	reg, _ := regexp.Compile("^\\S+\\.\\w+$")
-	return reg
-}
+			provider, err := stagingProvider(sg.CertProvider, staging)
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s/%s: %w", t.Tenant, t.Env, sg.GroupName, err)
+			}
 
-func validateCertMode(certMode string) bool {
-	switch {
-	case certMode == "":
-		return true
-	case slices.Contains(getAllowedCertModes(), certMode):
-		return true
-	case getCertModeRegex().MatchString(certMode):
-		return true
-	default:
-		return false
+			requests = append(requests, acme.Request{
+				Tenant:      t.Tenant,
+				Env:         t.Env,
+				GroupName:   sg.GroupName,
+				Sites:       sg.Sites,
+				Mode:        mode,
+				Provider:    provider,
+				KeyType:     acme.KeyType(sg.CertType),
+				Credentials: creds,
+			})
+		}
 	}
+	return requests, nil
 }
 
-func validateSite(site string, certMode string) bool {
-	switch {
-	case getCertModeRegex().MatchString(certMode) && getCertModeSiteRegex(certMode).MatchString(site):
-		return true
-	case getSimpleSiteRegex().MatchString(site) && !getCertModeRegex().MatchString(certMode):
-		return true
-	default:
-		return false
+// runRenewCommand drives certificate issuance/renewal for every site
+// group found in the given config files, reusing the same validation
+// path as the default command so a broken config is never provisioned
+// against.
+func runRenewCommand(cmdArgs []string) {
+	staging := false
+	var authHook, cleanupHook string
+	var configArgs []string
+	for _, arg := range cmdArgs {
+		switch {
+		case arg == "--staging":
+			staging = true
+		case strings.HasPrefix(arg, "--dns-auth-hook="):
+			authHook = strings.TrimPrefix(arg, "--dns-auth-hook=")
+		case strings.HasPrefix(arg, "--dns-cleanup-hook="):
+			cleanupHook = strings.TrimPrefix(arg, "--dns-cleanup-hook=")
+		default:
+			configArgs = append(configArgs, arg)
+		}
 	}
-}
 
-func validateCertProvider(certProvider string) bool {
-	switch {
-	case certProvider == "":
-		return true
-	case slices.Contains(getAllowedProviders(), certProvider):
-		return true
-	default:
-		return false
+	if len(configArgs) < 1 {
+		println("usage: validator renew [--staging] [--dns-auth-hook=script] [--dns-cleanup-hook=script] <config file or dir>")
+		os.Exit(1)
 	}
-}
 
-func validateCertType(certType string) bool {
-	switch {
-	case certType == "":
-		return true
-	case slices.Contains(getAllowedCertTypes(), certType):
-		return true
-	default:
-		return false
+	var jsonFilePaths []string
+	for _, cmdArg := range configArgs {
+		jsonFilePaths = append(jsonFilePaths, getFileOrDir(cmdArg)...)
 	}
-}
 
-func validateCertProviderCredentials(certProviderCredentials *EABCredentials) bool {
-	switch {
-	case certProviderCredentials == nil:
-		return true
-	case certProviderCredentials.Email != "" && certProviderCredentials.HMACKey != "" &&
-		certProviderCredentials.KID != "":
-		return true
-	default:
-		return false
+	var dns acme.DNSProvider
+	if authHook != "" {
+		dns = &acme.ExecDNSProvider{AuthHook: authHook, CleanupHook: cleanupHook}
 	}
-}
+	provisioner := acme.NewProvisioner(&acme.FileStore{BaseDir: acmeStoreDir}, dns)
+	ctx := context.Background()
 
-func prepareSGErrorMessage(checkType string, gotValue string, expectedValue string) string {
-	return "Incorrect SG field '" + checkType + "' value: '" + gotValue + "'. Value must be one of: " + expectedValue
-}
+	for _, jsonFilePath := range jsonFilePaths {
+		jsonFile, err := os.Open(jsonFilePath)
+		if err != nil {
+			println("No such file or directory: " + jsonFilePath)
+			os.Exit(1)
+		}
 
-func validateSGs(sgs []siteGroupStruct, errors *[]string) {
-	tenantSites := make(StringSet)
+		byteValue, _ := io.ReadAll(jsonFile)
+		_ = jsonFile.Close()
 
-	for _, siteGroupObj := range sgs {
-		if siteGroupObj.GroupName == "" {
-			*errors = append(*errors, "SG field 'group_name' not found or empty")
+		rep, err := validator.Validate(strings.NewReader(string(byteValue)))
+		if err != nil || rep.HasErrors() {
+			text, _ := report.Render(report.FormatText, "txc-validator", []report.Report{withFile(rep, jsonFilePath)})
+			println("Config file '" + jsonFilePath + "' failed validation, skipping renewal:\n" + text)
+			continue
 		}
 
-		if len(siteGroupObj.Sites) == 0 {
-			*errors = append(*errors, "Field 'sites' not found or empty")
-		} else {
-			for _, site := range siteGroupObj.Sites {
-				if _, ok := tenantSites[site]; !ok {
-					tenantSites[site] = struct{}{}
-				} else {
-					*errors = append(*errors, "Duplicate found for site '"+site+"'")
-				}
-			}
+		var tenants []validator.Tenant
+		if err := json.Unmarshal(byteValue, &tenants); err != nil {
+			println("Incorrect JSON format in " + jsonFilePath + ": " + err.Error())
+			os.Exit(1)
 		}
 
-		if !validateCertMode(siteGroupObj.CertMode) {
-			*errors = append(
-				*errors,
-				prepareSGErrorMessage(
-					"cert_mode",
-					siteGroupObj.CertMode,
-					strings.Join(getAllowedCertModes(), ", ")+
-						", or regex '"+getCertModeRegex().String()+"'",
-				),
-			)
-		}
-		if !validateCertProvider(siteGroupObj.CertProvider) {
-			*errors = append(
-				*errors,
-				prepareSGErrorMessage(
-					"cert_provider",
-					siteGroupObj.CertProvider,
-					strings.Join(getAllowedProviders(), ", "),
-				),
-			)
-		}
-		if !validateCertType(siteGroupObj.CertType) {
-			*errors = append(
-				*errors,
-				prepareSGErrorMessage(
-					"cert_type",
-					siteGroupObj.CertType,
-					strings.Join(getAllowedCertTypes(), ", "),
-				),
-			)
-		}
-		if !validateCertProviderCredentials(siteGroupObj.CertProviderCredentials) {
-			*errors = append(
-				*errors,
-				"Field 'cert_provider_creds' has incorrect format",
-			)
+		requests, err := buildAcmeRequests(tenants, staging)
+		if err != nil {
+			println("Renewal aborted for " + jsonFilePath + ": " + err.Error())
+			os.Exit(1)
 		}
-		for _, site := range siteGroupObj.Sites {
-			if !validateSite(site, siteGroupObj.CertMode) {
-				*errors = append(
-					*errors,
-					"Incorrect site field value: '"+site+"'. Value must correspond to site regex: '"+
-						getSimpleSiteRegex().String()+"' and cert_mode '"+siteGroupObj.CertMode+"'",
-				)
+
+		for _, req := range requests {
+			if err := provisioner.Renew(ctx, req); err != nil {
+				println("Renewal failed for " + req.Tenant + "/" + req.Env + "/" + req.GroupName + ": " + err.Error())
+				os.Exit(1)
 			}
 		}
 	}
 }
 
-func validateTenants(tenants []tenantStruct, errors *[]string) {
-	for _, tenantStructObj := range tenants {
+func withFile(rep report.Report, file string) report.Report {
+	rep.File = file
+	return rep
+}
 
-		if tenantStructObj.Tenant == "" {
-			*errors = append(*errors, "Field 'tenant' not found or empty")
-		}
-		if tenantStructObj.Env == "" {
-			*errors = append(*errors, "Field 'env' not found or empty")
+func runValidateCommand(cmdArgs []string) {
+	for _, arg := range cmdArgs {
+		if arg == "--schema" {
+			fmt.Print(string(schema.TXCertbot))
+			os.Exit(0)
 		}
-		if len(tenantStructObj.SiteGroups) == 0 {
-			*errors = append(*errors, "Field 'site_groups' not found or empty")
-			continue
-		}
-		validateSGs(tenantStructObj.SiteGroups, errors)
 	}
-}
 
-func getFileOrDir(path string) []string {
-	file, err := os.Stat(path)
+	format, cmdArgs, err := report.ParseFormatFlag(cmdArgs)
 	if err != nil {
 		println(err.Error())
 		os.Exit(1)
 	}
-	switch {
-	case file.Mode().IsDir():
-		if path[len(path)-1] != '/' {
-			path = path + "/"
-		}
-		jsonFiles, _ := filepath.Glob(path + "*.json")
-		return jsonFiles
-	case file.Mode().IsRegular():
-		fileExt := path[strings.LastIndex(path, ".")+1:]
-
-		if fileExt != "json" {
-			println(err)
-			os.Exit(1)
-		}
-		return []string{path}
-	default:
-		return []string{}
-	}
-}
 
-func main() {
-	cmdArgs := os.Args[1:]
 	if len(cmdArgs) < 1 {
-		println("usage: validator <command> <args>")
+		println("usage: validator [--format=text|json|sarif|github] <file or dir>... | --schema")
 		os.Exit(1)
 	}
 
 	var jsonFilePaths []string
-
 	for _, cmdArg := range cmdArgs {
 		jsonFilePaths = append(jsonFilePaths, getFileOrDir(cmdArg)...)
 	}
@@ -286,48 +224,54 @@ func main() {
 		os.Exit(1)
 	}
 
-	var errors []string
+	var reports []report.Report
+	failed := false
 
 	for _, jsonFilePath := range jsonFilePaths {
-		var runErrors []string
 		jsonFile, err := os.Open(jsonFilePath)
 		if err != nil {
 			println("No such file or directory: " + jsonFilePath)
 			os.Exit(1)
 		}
 
-		var tenantStructs []tenantStruct
-
-		byteValue, _ := io.ReadAll(jsonFile)
-
-		err = json.Unmarshal(byteValue, &tenantStructs)
+		rep, err := validator.Validate(jsonFile)
+		_ = jsonFile.Close()
 		if err != nil {
-			runErrors = append(
-				runErrors,
-				"Incorrect JSON format: "+err.Error(),
-			)
+			println("Failed to read " + jsonFilePath + ": " + err.Error())
+			os.Exit(1)
 		}
 
-		validateTenants(tenantStructs, &runErrors)
-
-		if len(runErrors) > 0 {
-			errorMsgPart := "Config file '" + jsonFilePath + "' validation failed.\nIssues:\n" +
-				"- " + strings.Join(runErrors, "\n- ")
-			errors = append(errors, errorMsgPart)
-		} else {
-			println("Config file " + jsonFilePath + " validation succeeded.")
+		rep = withFile(rep, jsonFilePath)
+		reports = append(reports, rep)
+		if rep.HasErrors() {
+			failed = true
 		}
-
-		_ = jsonFile.Close()
 	}
 
-	if len(errors) > 0 {
-		for _, err := range errors {
-			println(err)
-		}
+	out, err := report.Render(format, "txc-validator", reports)
+	if err != nil {
+		println(err.Error())
 		os.Exit(1)
 	}
+	fmt.Print(out)
 
+	if failed {
+		os.Exit(1)
+	}
 	os.Exit(0)
+}
+
+func main() {
+	cmdArgs := os.Args[1:]
+	if len(cmdArgs) < 1 {
+		println("usage: validator <command> <args>")
+		os.Exit(1)
+	}
+
+	if cmdArgs[0] == "renew" {
+		runRenewCommand(cmdArgs[1:])
+		return
+	}
 
+	runValidateCommand(cmdArgs)
 }
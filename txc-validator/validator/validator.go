@@ -0,0 +1,223 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: validator.go
+Description: TXCertbot 2.X compact Go config validator (library)
+Author: tengzl33t
+*/
+
+// Allow-lists and patterns (AllowedCertProviders, CertModeWildcardPattern,
+// ...) live in zz_generated.go, generated from schema/txcertbot.schema.json
+// via:
+//go:generate go run ../../internal/schemagen -schema ../../schema/txcertbot.schema.json -package validator -out zz_generated.go -enum CertProviders=/items/properties/site_groups/items/properties/cert_provider/enum -enum CertTypes=/items/properties/site_groups/items/properties/cert_type/enum -enum CertModes=/items/properties/site_groups/items/properties/cert_mode/oneOf/0/enum -pattern CertModeWildcard=/items/properties/site_groups/items/properties/cert_mode/oneOf/1/pattern -pattern SimpleSite=/items/properties/site_groups/items/properties/sites/items/pattern -pattern Identifier=/items/properties/tenant/pattern
+
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"tengzl33t/smallgoes/report"
+)
+
+type StringSet map[string]struct{}
+
+type SiteGroup struct {
+	GroupName               string          `json:"group_name"`
+	Sites                   []string        `json:"sites"`
+	CertMode                string          `json:"cert_mode"`
+	CertProvider            string          `json:"cert_provider"`
+	CertType                string          `json:"cert_type"`
+	CertProviderCredentials *EABCredentials `json:"cert_provider_creds"`
+}
+
+type Tenant struct {
+	Tenant     string      `json:"tenant"`
+	Env        string      `json:"env"`
+	SiteGroups []SiteGroup `json:"site_groups"`
+}
+
+type EABCredentials struct {
+	Email   string `json:"email"`
+	KID     string `json:"kid"`
+	HMACKey string `json:"hmac_key"`
+}
+
+func getCertModeRegex() *regexp.Regexp {
+	reg, _ := regexp.Compile(CertModeWildcardPattern)
+	return reg
+}
+
+func getCertModeSiteRegex(certMode string) *regexp.Regexp {
+	fixedMode := strings.ReplaceAll(certMode[2:], ".", "\\.")
+	reg, _ := regexp.Compile("^([a-zA-Z0-9-]+\\.)?" + fixedMode + "$")
+	return reg
+}
+
+func getSimpleSiteRegex() *regexp.Regexp {
+	reg, _ := regexp.Compile(SimpleSitePattern)
+	return reg
+}
+
+func getIdentifierRegex() *regexp.Regexp {
+	reg, _ := regexp.Compile(IdentifierPattern)
+	return reg
+}
+
+// validateIdentifier restricts tenant/env/group_name to a safe charset:
+// these values end up as path components under FileStore's BaseDir, so
+// letting through "/" or ".." would let a config escape it.
+func validateIdentifier(id string) bool {
+	return getIdentifierRegex().MatchString(id)
+}
+
+func validateCertMode(certMode string) bool {
+	switch {
+	case slices.Contains(AllowedCertModes, certMode):
+		return true
+	case getCertModeRegex().MatchString(certMode):
+		return true
+	default:
+		return false
+	}
+}
+
+func validateSite(site string, certMode string) bool {
+	switch {
+	case getCertModeRegex().MatchString(certMode) && getCertModeSiteRegex(certMode).MatchString(site):
+		return true
+	case getSimpleSiteRegex().MatchString(site) && !getCertModeRegex().MatchString(certMode):
+		return true
+	default:
+		return false
+	}
+}
+
+func validateCertProvider(certProvider string) bool {
+	return slices.Contains(AllowedCertProviders, certProvider)
+}
+
+func validateCertType(certType string) bool {
+	return slices.Contains(AllowedCertTypes, certType)
+}
+
+func validateCertProviderCredentials(certProviderCredentials *EABCredentials) bool {
+	switch {
+	case certProviderCredentials == nil:
+		return true
+	case certProviderCredentials.Email != "" && certProviderCredentials.HMACKey != "" &&
+		certProviderCredentials.KID != "":
+		return true
+	default:
+		return false
+	}
+}
+
+func validateSGs(sgs []SiteGroup, tenantIdx int, rep *report.Report) {
+	tenantSites := make(StringSet)
+
+	for sgIdx, sg := range sgs {
+		path := fmt.Sprintf("/%d/site_groups/%d", tenantIdx, sgIdx)
+
+		if sg.GroupName == "" {
+			rep.Errorf(path+"/group_name", "site_group.group_name.required", "SG field 'group_name' not found or empty")
+		} else if !validateIdentifier(sg.GroupName) {
+			rep.Errorf(path+"/group_name", "site_group.group_name.invalid",
+				"Field 'group_name' may only contain letters, digits, underscores and hyphens")
+		}
+
+		if len(sg.Sites) == 0 {
+			rep.Errorf(path+"/sites", "site_group.sites.required", "Field 'sites' not found or empty")
+		} else {
+			for siteIdx, site := range sg.Sites {
+				if _, ok := tenantSites[site]; !ok {
+					tenantSites[site] = struct{}{}
+				} else {
+					rep.Errorf(fmt.Sprintf("%s/sites/%d", path, siteIdx), "site_group.sites.duplicate",
+						"Duplicate found for site '"+site+"'")
+				}
+			}
+		}
+
+		if !validateCertMode(sg.CertMode) {
+			rep.Errorf(path+"/cert_mode", "site_group.cert_mode.invalid",
+				"Incorrect SG field 'cert_mode' value: '"+sg.CertMode+"'. Value must be one of: "+
+					strings.Join(AllowedCertModes, ", ")+", or regex '"+getCertModeRegex().String()+"'")
+		}
+		if !validateCertProvider(sg.CertProvider) {
+			rep.Errorf(path+"/cert_provider", "site_group.cert_provider.invalid",
+				"Incorrect SG field 'cert_provider' value: '"+sg.CertProvider+"'. Value must be one of: "+
+					strings.Join(AllowedCertProviders, ", "))
+		}
+		if !validateCertType(sg.CertType) {
+			rep.Errorf(path+"/cert_type", "site_group.cert_type.invalid",
+				"Incorrect SG field 'cert_type' value: '"+sg.CertType+"'. Value must be one of: "+
+					strings.Join(AllowedCertTypes, ", "))
+		}
+		if !validateCertProviderCredentials(sg.CertProviderCredentials) {
+			rep.Errorf(path+"/cert_provider_creds", "site_group.cert_provider_creds.invalid",
+				"Field 'cert_provider_creds' has incorrect format")
+		}
+		for siteIdx, site := range sg.Sites {
+			if !validateSite(site, sg.CertMode) {
+				rep.Errorf(fmt.Sprintf("%s/sites/%d", path, siteIdx), "site_group.sites.invalid",
+					"Incorrect site field value: '"+site+"'. Value must correspond to site regex: '"+
+						getSimpleSiteRegex().String()+"' and cert_mode '"+sg.CertMode+"'")
+			}
+		}
+	}
+}
+
+func validateTenants(tenants []Tenant, rep *report.Report) {
+	for tenantIdx, t := range tenants {
+		path := fmt.Sprintf("/%d", tenantIdx)
+
+		if t.Tenant == "" {
+			rep.Errorf(path+"/tenant", "tenant.tenant.required", "Field 'tenant' not found or empty")
+		} else if !validateIdentifier(t.Tenant) {
+			rep.Errorf(path+"/tenant", "tenant.tenant.invalid",
+				"Field 'tenant' may only contain letters, digits, underscores and hyphens")
+		}
+		if t.Env == "" {
+			rep.Errorf(path+"/env", "tenant.env.required", "Field 'env' not found or empty")
+		} else if !validateIdentifier(t.Env) {
+			rep.Errorf(path+"/env", "tenant.env.invalid",
+				"Field 'env' may only contain letters, digits, underscores and hyphens")
+		}
+		if len(t.SiteGroups) == 0 {
+			rep.Errorf(path+"/site_groups", "tenant.site_groups.required", "Field 'site_groups' not found or empty")
+			continue
+		}
+		validateSGs(t.SiteGroups, tenantIdx, rep)
+	}
+}
+
+// Validate reads a TXCertbot config document from r and returns a
+// structured Report of every diagnostic found. A non-nil error means
+// the document wasn't valid JSON at all; Report.HasErrors() is what
+// callers should check for schema/rule violations.
+func Validate(r io.Reader) (report.Report, error) {
+	var rep report.Report
+
+	byteValue, err := io.ReadAll(r)
+	if err != nil {
+		return rep, err
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(byteValue, &tenants); err != nil {
+		rep.Errorf("", "document.json.invalid", "Incorrect JSON format: "+err.Error())
+		return rep, nil
+	}
+
+	validateTenants(tenants, &rep)
+	return rep, nil
+}
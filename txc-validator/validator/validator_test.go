@@ -0,0 +1,138 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: validator_test.go
+Description: Tests for TXCertbot schema-driven validation
+Author: tengzl33t
+*/
+
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateValidDocument(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"env": "prod",
+		"site_groups": [{
+			"group_name": "main",
+			"sites": ["www.example.com"],
+			"cert_mode": "classic",
+			"cert_provider": "letsencrypt",
+			"cert_type": "ec-256"
+		}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rep.HasErrors() {
+		t.Fatalf("expected no diagnostics, got %v", rep.Diagnostics)
+	}
+}
+
+func TestValidateWildcardSite(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"env": "prod",
+		"site_groups": [{
+			"group_name": "wildcard",
+			"sites": ["sub.example.com"],
+			"cert_mode": "*.example.com",
+			"cert_provider": "letsencrypt",
+			"cert_type": "ec-256"
+		}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rep.HasErrors() {
+		t.Fatalf("expected no diagnostics for a valid wildcard group, got %v", rep.Diagnostics)
+	}
+}
+
+func TestValidateInvalidCertProvider(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"env": "prod",
+		"site_groups": [{
+			"group_name": "main",
+			"sites": ["www.example.com"],
+			"cert_mode": "classic",
+			"cert_provider": "not-a-real-provider",
+			"cert_type": "ec-256"
+		}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rep.HasErrors() {
+		t.Fatalf("expected a diagnostic for an invalid cert_provider")
+	}
+}
+
+func TestValidateDuplicateSite(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"env": "prod",
+		"site_groups": [{
+			"group_name": "main",
+			"sites": ["www.example.com", "www.example.com"],
+			"cert_mode": "classic",
+			"cert_provider": "letsencrypt",
+			"cert_type": "ec-256"
+		}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rep.HasErrors() {
+		t.Fatalf("expected a diagnostic for a duplicate site")
+	}
+}
+
+func TestValidateRejectsPathTraversalTenant(t *testing.T) {
+	doc := `[{
+		"tenant": "../../../../tmp/evil",
+		"env": "prod",
+		"site_groups": [{
+			"group_name": "main",
+			"sites": ["www.example.com"],
+			"cert_mode": "classic",
+			"cert_provider": "letsencrypt",
+			"cert_type": "ec-256"
+		}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rep.HasErrors() {
+		t.Fatalf("expected a diagnostic for a tenant containing path separators")
+	}
+}
+
+func TestValidateMalformedJSON(t *testing.T) {
+	rep, err := Validate(strings.NewReader("not json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rep.HasErrors() {
+		t.Fatalf("expected a diagnostic for malformed JSON")
+	}
+}
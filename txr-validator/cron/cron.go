@@ -0,0 +1,326 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: cron.go
+Description: Semantic validation and next-fire computation for the
+cron_to_send field, replacing the old syntax-only regex
+Author: tengzl33t
+*/
+
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fieldSpec describes one of the 5-7 fields a cron_to_send expression
+// can have: its human name (used in error messages), valid range, and
+// the named tokens it accepts in addition to numbers.
+type fieldSpec struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dowNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+var (
+	secondSpec = fieldSpec{"second", 0, 59, nil}
+	minuteSpec = fieldSpec{"minute", 0, 59, nil}
+	hourSpec   = fieldSpec{"hour", 0, 23, nil}
+	domSpec    = fieldSpec{"day of month", 1, 31, nil}
+	monthSpec  = fieldSpec{"month", 1, 12, monthNames}
+	dowSpec    = fieldSpec{"day of week", 0, 7, dowNames}
+	yearSpec   = fieldSpec{"year", 1970, 2099, nil}
+)
+
+// FieldError is one semantically invalid field in a cron expression,
+// e.g. `minute field "60": out of range 0-59`.
+type FieldError struct {
+	Field   string
+	Raw     string
+	Message string
+}
+
+func (e FieldError) Error() string { return e.Message }
+
+// Schedule is a parsed, semantically valid cron_to_send expression.
+type Schedule struct {
+	seconds, minutes, hours, doms, months, dows, years map[int]bool
+
+	// domRestricted and dowRestricted record whether the day-of-month and
+	// day-of-week fields were written as anything other than "*". Standard
+	// cron semantics OR these two fields together when both are restricted
+	// (e.g. "1,15 * MON" fires on the 1st, the 15th, and every Monday)
+	// rather than ANDing them, which would only fire when a Monday happens
+	// to land on the 1st or 15th.
+	domRestricted, dowRestricted bool
+}
+
+// Parse validates expr against the standard 5-field cron form plus the
+// 6-field (leading seconds) and 7-field (trailing year) extensions. All
+// field errors are returned together rather than stopping at the first
+// one, so a single bad config file is diagnosed in one pass.
+func Parse(expr string) (*Schedule, []FieldError) {
+	fields := strings.Fields(expr)
+
+	var specs []fieldSpec
+	switch len(fields) {
+	case 5:
+		specs = []fieldSpec{minuteSpec, hourSpec, domSpec, monthSpec, dowSpec}
+	case 6:
+		specs = []fieldSpec{secondSpec, minuteSpec, hourSpec, domSpec, monthSpec, dowSpec}
+	case 7:
+		specs = []fieldSpec{secondSpec, minuteSpec, hourSpec, domSpec, monthSpec, dowSpec, yearSpec}
+	default:
+		return nil, []FieldError{{
+			Message: fmt.Sprintf("expected 5, 6, or 7 whitespace-separated fields, got %d", len(fields)),
+		}}
+	}
+
+	sched := &Schedule{}
+	var errs []FieldError
+
+	sets := make([]map[int]bool, len(specs))
+	for i, spec := range specs {
+		set, fieldErrs := parseField(fields[i], spec)
+		sets[i] = set
+		errs = append(errs, fieldErrs...)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	for i, spec := range specs {
+		switch spec.name {
+		case domSpec.name:
+			sched.domRestricted = fields[i] != "*"
+		case dowSpec.name:
+			sched.dowRestricted = fields[i] != "*"
+		}
+	}
+
+	idx := 0
+	if len(fields) == 6 || len(fields) == 7 {
+		sched.seconds = sets[idx]
+		idx++
+	} else {
+		sched.seconds = map[int]bool{0: true}
+	}
+	sched.minutes = sets[idx]
+	idx++
+	sched.hours = sets[idx]
+	idx++
+	sched.doms = sets[idx]
+	idx++
+	sched.months = sets[idx]
+	idx++
+	sched.dows = sets[idx]
+	idx++
+	if len(fields) == 7 {
+		sched.years = sets[idx]
+	}
+
+	return sched, nil
+}
+
+func parseField(raw string, spec fieldSpec) (map[int]bool, []FieldError) {
+	set := map[int]bool{}
+	var errs []FieldError
+
+	for _, part := range strings.Split(raw, ",") {
+		values, err := parseFieldPart(part, spec)
+		if err != nil {
+			errs = append(errs, FieldError{
+				Field:   spec.name,
+				Raw:     part,
+				Message: fmt.Sprintf("%s field %q: %s", spec.name, part, err.Error()),
+			})
+			continue
+		}
+		for _, v := range values {
+			set[v] = true
+		}
+	}
+
+	return set, errs
+}
+
+func parseFieldPart(part string, spec fieldSpec) ([]int, error) {
+	base, step, err := splitStep(part)
+	if err != nil {
+		return nil, err
+	}
+
+	var low, high int
+	switch {
+	case base == "*":
+		low, high = spec.min, spec.max
+	case strings.Contains(base, "-"):
+		bounds := strings.SplitN(base, "-", 2)
+		low, err = parseValue(bounds[0], spec)
+		if err != nil {
+			return nil, err
+		}
+		high, err = parseValue(bounds[1], spec)
+		if err != nil {
+			return nil, err
+		}
+		if low > high {
+			return nil, fmt.Errorf("range start %d is after range end %d", low, high)
+		}
+	default:
+		v, err := parseValue(base, spec)
+		if err != nil {
+			return nil, err
+		}
+		low, high = v, v
+	}
+
+	if low < spec.min || high > spec.max {
+		return nil, fmt.Errorf("out of range %d-%d", spec.min, spec.max)
+	}
+
+	var values []int
+	for v := low; v <= high; v += step {
+		values = append(values, normalizeDOW(spec, v))
+	}
+	return values, nil
+}
+
+func splitStep(part string) (base string, step int, err error) {
+	if !strings.Contains(part, "/") {
+		return part, 1, nil
+	}
+	pieces := strings.SplitN(part, "/", 2)
+	step, err = strconv.Atoi(pieces[1])
+	if err != nil || step < 1 {
+		return "", 0, fmt.Errorf("invalid step value %q", pieces[1])
+	}
+	return pieces[0], step, nil
+}
+
+func parseValue(raw string, spec fieldSpec) (int, error) {
+	if spec.names != nil {
+		if v, ok := spec.names[strings.ToUpper(raw)]; ok {
+			return v, nil
+		}
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognized value %q", raw)
+	}
+	if v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("out of range %d-%d", spec.min, spec.max)
+	}
+	return v, nil
+}
+
+// normalizeDOW folds day-of-week 7 onto 0, since both mean Sunday.
+func normalizeDOW(spec fieldSpec, v int) int {
+	if spec.name == dowSpec.name && v == 7 {
+		return 0
+	}
+	return v
+}
+
+// dayMatches reports whether t's day-of-month and day-of-week satisfy the
+// schedule, applying the standard cron rule: when both fields are
+// restricted (not "*"), a day matching either one is enough; when only one
+// is restricted, that field alone decides the match.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.doms[t.Day()]
+	dowMatch := s.dows[int(t.Weekday())]
+
+	switch {
+	case s.domRestricted && s.dowRestricted:
+		return domMatch || dowMatch
+	case s.domRestricted:
+		return domMatch
+	case s.dowRestricted:
+		return dowMatch
+	default:
+		return domMatch && dowMatch
+	}
+}
+
+// Next returns the first match strictly after from, or the zero Time if
+// none is found within four years (a schedule that never fires again,
+// e.g. an impossible day-of-month/month combination). It skips ahead a
+// whole field at a time (month, then day, then hour, ...) instead of
+// scanning second by second, since a naive scan over an empty schedule
+// would mean hundreds of millions of iterations.
+func (s *Schedule) Next(from time.Time) time.Time {
+	t := from.Truncate(time.Second).Add(time.Second)
+	limit := from.AddDate(4, 0, 0)
+
+	for t.Before(limit) {
+		if s.years != nil && !s.years[t.Year()] {
+			t = time.Date(t.Year()+1, 1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+			continue
+		}
+		if !s.seconds[t.Second()] {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// NextN returns up to n successive fire times after from.
+func (s *Schedule) NextN(from time.Time, n int) []time.Time {
+	var times []time.Time
+	cursor := from
+	for i := 0; i < n; i++ {
+		next := s.Next(cursor)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+		cursor = next
+	}
+	return times
+}
+
+// AverageInterval returns the mean gap between consecutive entries in
+// times, or 0 if there are fewer than two.
+func AverageInterval(times []time.Time) time.Duration {
+	if len(times) < 2 {
+		return 0
+	}
+	total := times[len(times)-1].Sub(times[0])
+	return total / time.Duration(len(times)-1)
+}
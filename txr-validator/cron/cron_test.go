@@ -0,0 +1,106 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: cron_test.go
+Description: Tests for cron field parsing and next-fire computation
+Author: tengzl33t
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFieldCount(t *testing.T) {
+	if _, errs := Parse("* * * *"); len(errs) == 0 {
+		t.Fatalf("expected an error for a 4-field expression")
+	}
+	if _, errs := Parse("0 9 1 1 1"); len(errs) != 0 {
+		t.Fatalf("unexpected errors for a valid 5-field expression: %v", errs)
+	}
+}
+
+func TestParseOutOfRange(t *testing.T) {
+	_, errs := Parse("0 9 1 1 8")
+	if len(errs) == 0 {
+		t.Fatalf("expected an error for day-of-week 8")
+	}
+}
+
+func TestParseNamedTokens(t *testing.T) {
+	sched, errs := Parse("0 9 * * MON")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if !sched.dows[1] {
+		t.Fatalf("expected MON to resolve to weekday 1")
+	}
+}
+
+func TestNextSimpleDaily(t *testing.T) {
+	sched, errs := Parse("0 9 * * *")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := sched.Next(from)
+	want := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	if !next.Equal(want) {
+		t.Fatalf("got %v, want %v", next, want)
+	}
+}
+
+// TestNextDomDowOR checks that a schedule with both day-of-month and
+// day-of-week restricted fires on a day matching either field, not just
+// days where both happen to align.
+func TestNextDomDowOR(t *testing.T) {
+	sched, errs := Parse("0 9 1,15 * MON")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	// 2026-01-01 is a Thursday: matches day-of-month only.
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := sched.Next(from)
+	want := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	if !next.Equal(want) {
+		t.Fatalf("got %v, want %v (day-of-month match)", next, want)
+	}
+
+	// The following Monday, 2026-01-05, matches day-of-week only.
+	from = time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	next = sched.Next(from)
+	want = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	if !next.Equal(want) {
+		t.Fatalf("got %v, want %v (day-of-week match)", next, want)
+	}
+}
+
+func TestNextUnsatisfiable(t *testing.T) {
+	sched, errs := Parse("0 0 30 2 *")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if next := sched.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+		t.Fatalf("expected zero time for Feb 30, got %v", next)
+	}
+}
+
+func TestAverageInterval(t *testing.T) {
+	times := []time.Time{
+		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC),
+	}
+	got := AverageInterval(times)
+	want := 36 * time.Hour
+	if got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
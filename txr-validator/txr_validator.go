@@ -6,7 +6,7 @@ file, You can obtain one at https://mozilla.org/MPL/2.0/.
 SPDX-License-Identifier: MPL-2.0
 
 File: txr_validator.go
-Description: TXReports 2.X compact Go config validator
+Description: TXReports 2.X compact Go config validator (CLI)
 Author: tengzl33t
 
 Better to compile with tinygo:
@@ -17,87 +17,18 @@ package main
 
 import (
 	"encoding/json"
-	"io"
+	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
-)
-
-type scheduleStruct struct {
-	DaysToCollect int    `json:"days_to_collect"`
-	CronToSend    string `json:"cron_to_send"`
-}
-
-type featureStruct struct {
-	Rules    *bool `json:"rules"`
-	Entities *bool `json:"entities"`
-	Stats    *bool `json:"stats"`
-	Matches  *bool `json:"matches"`
-	Blocks   *bool `json:"blocks"`
-}
-
-type tenantStruct struct {
-	Tenant    string           `json:"tenant"`
-	LogoPath  *string          `json:"logo_path"`
-	Emails    []string         `json:"emails"`
-	Schedules []scheduleStruct `json:"schedules"`
-	Features  *featureStruct   `json:"features"`
-}
-
-func getLogoRegex() *regexp.Regexp {
-	return regexp.MustCompile(`.+\.(?:jpg|jpeg|png|svg|tiff|tif|webp|gif|bmp|avif|jfif)$`)
-}
-
-func getCronRegex() *regexp.Regexp {
-	return regexp.MustCompile(`^((((\d+,)+\d+|(\d+([/\-])\d+)|\d+|\*(/\d+)?|[A-Z]{3}(-[A-Z]{3})?) ?){5,7})$`)
-}
-
-func checkEmails(emails []string) bool {
-	for _, email := range emails {
-		if !strings.Contains(email, "@") {
-			return false
-		}
-	}
-	return true
-}
-
-func validateSchedules(schedules []scheduleStruct, errors *[]string) {
-	for _, schedule := range schedules {
-		if schedule.DaysToCollect > 366 || schedule.DaysToCollect < 1 {
-			*errors = append(*errors, "Field 'days_to_collect' can't be greater than 366 or less than 1")
-		}
-		if !getCronRegex().MatchString(schedule.CronToSend) {
-			*errors = append(*errors, "Field 'cron_to_send' has incorrect format")
-		}
-	}
-}
-
-func validateTenants(tenants []tenantStruct, errors *[]string) {
-	for _, tenantStructObj := range tenants {
+	"time"
 
-		if tenantStructObj.Tenant == "" {
-			*errors = append(*errors, "Field 'tenant' not found or empty")
-		}
-		if tenantStructObj.LogoPath != nil && !getLogoRegex().MatchString(*tenantStructObj.LogoPath) {
-			*errors = append(*errors, "Field 'logo_path' does not match the expected format")
-		}
-		if len(tenantStructObj.Emails) == 0 {
-			*errors = append(*errors, "Field 'emails' not found or empty")
-		} else {
-			if !checkEmails(tenantStructObj.Emails) {
-				*errors = append(*errors, "Field 'emails' has incorrect entries")
-			}
-		}
-		if len(tenantStructObj.Schedules) == 0 {
-			*errors = append(*errors, "Field 'schedules' not found or empty")
-			continue
-		}
-
-		validateSchedules(tenantStructObj.Schedules, errors)
-
-	}
-}
+	"tengzl33t/smallgoes/report"
+	"tengzl33t/smallgoes/schema"
+	"tengzl33t/smallgoes/txr-validator/cron"
+	"tengzl33t/smallgoes/txr-validator/validator"
+)
 
 func getFileOrDir(path string) []string {
 	file, err := os.Stat(path)
@@ -125,15 +56,89 @@ func getFileOrDir(path string) []string {
 	}
 }
 
+// parseNextFlag pulls the first "--next=N" argument out of args,
+// defaulting to 0 (no preview) when absent.
+func parseNextFlag(args []string) (int, []string, error) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--next=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--next="))
+			if err != nil || n < 0 {
+				return 0, nil, fmt.Errorf("invalid --next value %q: must be a non-negative integer", strings.TrimPrefix(arg, "--next="))
+			}
+			rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return n, rest, nil
+		}
+	}
+	return 0, args, nil
+}
+
+// printCronPreview prints the next n fire times (and the average
+// interval between them) for every schedule in jsonFilePath, so an
+// operator can sanity-check that "every Monday 9am" means what they
+// think it means.
+func printCronPreview(jsonFilePath string, n int) {
+	data, err := os.ReadFile(jsonFilePath)
+	if err != nil {
+		return
+	}
+
+	var tenants []validator.Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, t := range tenants {
+		for _, sched := range t.Schedules {
+			parsed, fieldErrs := cron.Parse(sched.CronToSend)
+			if len(fieldErrs) > 0 {
+				continue
+			}
+
+			fmt.Printf("%s: cron '%s' next %d fire times:\n", t.Tenant, sched.CronToSend, n)
+			fires := parsed.NextN(now, n)
+			for _, fire := range fires {
+				fmt.Printf("  %s\n", fire.Format(time.RFC3339))
+			}
+			if avg := cron.AverageInterval(fires); avg > 0 {
+				fmt.Printf("  average interval: %s\n", avg)
+			}
+		}
+	}
+}
+
 func main() {
 	cmdArgs := os.Args[1:]
 	if len(cmdArgs) < 1 {
-		println("usage: validator <file or dir path>")
+		println("usage: validator [--format=text|json|sarif|github] [--next=N] <file or dir path> | --schema")
 		os.Exit(1)
 	}
 
-	var jsonFilePaths []string
+	for _, arg := range cmdArgs {
+		if arg == "--schema" {
+			fmt.Print(string(schema.TXReports))
+			os.Exit(0)
+		}
+	}
+
+	format, cmdArgs, err := report.ParseFormatFlag(cmdArgs)
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+
+	next, cmdArgs, err := parseNextFlag(cmdArgs)
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+
+	if len(cmdArgs) < 1 {
+		println("usage: validator [--format=text|json|sarif|github] [--next=N] <file or dir path>")
+		os.Exit(1)
+	}
 
+	var jsonFilePaths []string
 	for _, cmdArg := range cmdArgs {
 		jsonFilePaths = append(jsonFilePaths, getFileOrDir(cmdArg)...)
 	}
@@ -143,48 +148,41 @@ func main() {
 		os.Exit(1)
 	}
 
-	var errors []string
+	var reports []report.Report
+	failed := false
 
 	for _, jsonFilePath := range jsonFilePaths {
-		var runErrors []string
 		jsonFile, err := os.Open(jsonFilePath)
 		if err != nil {
 			println("No such file or directory: " + jsonFilePath)
 			os.Exit(1)
 		}
 
-		var tenantStructs []tenantStruct
-
-		byteValue, _ := io.ReadAll(jsonFile)
-
-		err = json.Unmarshal(byteValue, &tenantStructs)
+		rep, err := validator.Validate(jsonFile)
+		_ = jsonFile.Close()
 		if err != nil {
-			runErrors = append(
-				runErrors,
-				"Incorrect JSON format: "+err.Error(),
-			)
+			println("Failed to read " + jsonFilePath + ": " + err.Error())
+			os.Exit(1)
 		}
 
-		validateTenants(tenantStructs, &runErrors)
-
-		if len(runErrors) > 0 {
-			errorMsgPart := "Config file '" + jsonFilePath + "' validation failed.\nIssues:\n" +
-				"- " + strings.Join(runErrors, "\n- ")
-			errors = append(errors, errorMsgPart)
-		} else {
-			println("Config file " + jsonFilePath + " validation succeeded.")
+		rep.File = jsonFilePath
+		reports = append(reports, rep)
+		if rep.HasErrors() {
+			failed = true
+		} else if next > 0 {
+			printCronPreview(jsonFilePath, next)
 		}
-
-		_ = jsonFile.Close()
 	}
 
-	if len(errors) > 0 {
-		for _, err := range errors {
-			println(err)
-		}
+	out, err := report.Render(format, "txr-validator", reports)
+	if err != nil {
+		println(err.Error())
 		os.Exit(1)
 	}
+	fmt.Print(out)
 
+	if failed {
+		os.Exit(1)
+	}
 	os.Exit(0)
-
 }
@@ -0,0 +1,123 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: validator.go
+Description: TXReports 2.X compact Go config validator (library)
+Author: tengzl33t
+*/
+
+// LogoPathPattern and the days_to_collect bounds live in zz_generated.go,
+// generated from schema/txreports.schema.json via:
+//go:generate go run ../../internal/schemagen -schema ../../schema/txreports.schema.json -package validator -out zz_generated.go -pattern LogoPath=/items/properties/logo_path/pattern -range DaysToCollect=/items/properties/schedules/items/properties/days_to_collect/minimum,/items/properties/schedules/items/properties/days_to_collect/maximum
+
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"tengzl33t/smallgoes/report"
+	"tengzl33t/smallgoes/txr-validator/cron"
+)
+
+type Schedule struct {
+	DaysToCollect int    `json:"days_to_collect"`
+	CronToSend    string `json:"cron_to_send"`
+}
+
+type Features struct {
+	Rules    *bool `json:"rules"`
+	Entities *bool `json:"entities"`
+	Stats    *bool `json:"stats"`
+	Matches  *bool `json:"matches"`
+	Blocks   *bool `json:"blocks"`
+}
+
+type Tenant struct {
+	Tenant    string     `json:"tenant"`
+	LogoPath  *string    `json:"logo_path"`
+	Emails    []string   `json:"emails"`
+	Schedules []Schedule `json:"schedules"`
+	Features  *Features  `json:"features"`
+}
+
+func getLogoRegex() *regexp.Regexp {
+	return regexp.MustCompile(LogoPathPattern)
+}
+
+func checkEmails(emails []string) bool {
+	for _, email := range emails {
+		if !strings.Contains(email, "@") {
+			return false
+		}
+	}
+	return true
+}
+
+func validateSchedules(schedules []Schedule, tenantIdx int, rep *report.Report) {
+	for schedIdx, schedule := range schedules {
+		path := fmt.Sprintf("/%d/schedules/%d", tenantIdx, schedIdx)
+
+		if schedule.DaysToCollect > DaysToCollectMax || schedule.DaysToCollect < DaysToCollectMin {
+			rep.Errorf(path+"/days_to_collect", "schedule.days_to_collect.range",
+				fmt.Sprintf("Field 'days_to_collect' can't be greater than %d or less than %d", DaysToCollectMax, DaysToCollectMin))
+		}
+		if _, fieldErrs := cron.Parse(schedule.CronToSend); len(fieldErrs) > 0 {
+			for _, fieldErr := range fieldErrs {
+				rep.Errorf(path+"/cron_to_send", "schedule.cron_to_send.invalid", fieldErr.Message)
+			}
+		}
+	}
+}
+
+func validateTenants(tenants []Tenant, rep *report.Report) {
+	for tenantIdx, t := range tenants {
+		path := fmt.Sprintf("/%d", tenantIdx)
+
+		if t.Tenant == "" {
+			rep.Errorf(path+"/tenant", "tenant.tenant.required", "Field 'tenant' not found or empty")
+		}
+		if t.LogoPath != nil && !getLogoRegex().MatchString(*t.LogoPath) {
+			rep.Errorf(path+"/logo_path", "tenant.logo_path.invalid", "Field 'logo_path' does not match the expected format")
+		}
+		if len(t.Emails) == 0 {
+			rep.Errorf(path+"/emails", "tenant.emails.required", "Field 'emails' not found or empty")
+		} else if !checkEmails(t.Emails) {
+			rep.Errorf(path+"/emails", "tenant.emails.invalid", "Field 'emails' has incorrect entries")
+		}
+		if len(t.Schedules) == 0 {
+			rep.Errorf(path+"/schedules", "tenant.schedules.required", "Field 'schedules' not found or empty")
+			continue
+		}
+		validateSchedules(t.Schedules, tenantIdx, rep)
+	}
+}
+
+// Validate reads a TXReports config document from r and returns a
+// structured Report of every diagnostic found. A non-nil error means
+// the document wasn't valid JSON at all; Report.HasErrors() is what
+// callers should check for schema/rule violations.
+func Validate(r io.Reader) (report.Report, error) {
+	var rep report.Report
+
+	byteValue, err := io.ReadAll(r)
+	if err != nil {
+		return rep, err
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(byteValue, &tenants); err != nil {
+		rep.Errorf("", "document.json.invalid", "Incorrect JSON format: "+err.Error())
+		return rep, nil
+	}
+
+	validateTenants(tenants, &rep)
+	return rep, nil
+}
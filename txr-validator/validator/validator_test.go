@@ -0,0 +1,82 @@
+/*
+This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.
+
+SPDX-License-Identifier: MPL-2.0
+
+File: validator_test.go
+Description: Tests for TXReports schema-driven validation
+Author: tengzl33t
+*/
+
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateValidDocument(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"emails": ["ops@acme-corp.example"],
+		"schedules": [{"days_to_collect": 7, "cron_to_send": "0 9 * * MON"}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rep.HasErrors() {
+		t.Fatalf("expected no diagnostics, got %v", rep.Diagnostics)
+	}
+}
+
+func TestValidateInvalidCron(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"emails": ["ops@acme-corp.example"],
+		"schedules": [{"days_to_collect": 7, "cron_to_send": "99 9 * * MON"}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rep.HasErrors() {
+		t.Fatalf("expected a diagnostic for an out-of-range minute field")
+	}
+}
+
+func TestValidateDaysToCollectOutOfRange(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"emails": ["ops@acme-corp.example"],
+		"schedules": [{"days_to_collect": 999, "cron_to_send": "0 9 * * MON"}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rep.HasErrors() {
+		t.Fatalf("expected a diagnostic for days_to_collect out of range")
+	}
+}
+
+func TestValidateInvalidEmail(t *testing.T) {
+	doc := `[{
+		"tenant": "acme-corp",
+		"emails": ["not-an-email"],
+		"schedules": [{"days_to_collect": 7, "cron_to_send": "0 9 * * MON"}]
+	}]`
+
+	rep, err := Validate(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rep.HasErrors() {
+		t.Fatalf("expected a diagnostic for an invalid email")
+	}
+}